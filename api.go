@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	_ "github.com/smnov/cartest/docs"
@@ -13,59 +15,202 @@ import (
 
 type APIFunc func(w http.ResponseWriter, r *http.Request) error
 
-type APIError struct {
-	Error string `json:"error"`
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, FieldValidationError(err, "id", "invalid_integer", "invalid id")
+	}
+	return id, nil
 }
 
 // @Summary      GetCarsHandler
-// @Description  Get a list of cars with pagination support
+// @Description  Get a list of cars with pagination support. Text filters are case-insensitive;
+// @Description  *_prefix and *_contains parameters match partially.
+// @Description  Passing cursor or limit switches to keyset pagination: page and page_size are ignored and
+// @Description  the response carries nextCursor to fetch the following page with the same sort order.
 // @Tags         cars
 // @Accept       json
 // @Produce      json
-// @Param        page query int true "Page number"
-// @Param        page_size query int true "Number of items per page"
-// @Param make query string false "Car make"
-// @Param model query string false "Car model"
-// @Param year query int false "Car year"
-// @Success      200 {array} Car "Successful response with an array of cars"
+// @Param        page query int true "Page number (at most 1000000)"
+// @Param        page_size query int true "Number of items per page (at most 100)"
+// @Param        cursor query string false "Opaque cursor from nextCursor of the previous page"
+// @Param        limit query int false "Number of items per page in keyset mode (at most 100, default 20)"
+// @Param        make query string false "Car mark (alias of mark)"
+// @Param        mark query string false "Car mark"
+// @Param        marks query string false "Comma-separated list of car marks"
+// @Param        model query string false "Car model"
+// @Param        year query int false "Car year"
+// @Param        year_from query int false "Minimum car year"
+// @Param        year_to query int false "Maximum car year"
+// @Param        mark_prefix query string false "Car mark prefix"
+// @Param        mark_contains query string false "Car mark substring"
+// @Param        model_prefix query string false "Car model prefix"
+// @Param        model_contains query string false "Car model substring"
+// @Param        regNum_prefix query string false "Registration number prefix"
+// @Param        regNum_contains query string false "Registration number substring"
+// @Param        owner_name query string false "Owner name prefix"
+// @Param        owner_surname query string false "Owner surname prefix"
+// @Param        owner_patronymic query string false "Owner patronymic prefix"
+// @Param        sort query string false "Comma-separated sort keys: id, year, mark, regNum, owner.surname; prefix with - for descending"
+// @Success      200 {object} PageResponse[Car] "Page of cars with pagination metadata, also described by the Link and X-Total-Count headers. In keyset mode the body has items, limit, nextCursor and next instead"
 // @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
 // @Failure      404 {object} APIError "Resource not found"
 // @Failure      500 {object} APIError "Internal server error"
 // @Router       /cars/get [get]
 func (s *Server) GetCarsHandler(w http.ResponseWriter, r *http.Request) error {
-	pageStr := r.URL.Query().Get("page")
-	pageSizeStr := r.URL.Query().Get("page_size")
+	var v Validator
+	query := CarQuery{
+		Filter: ParseCarFilter(r, &v),
+		Sort:   ParseCarSort(r, &v),
+	}
+	if !ParseCarCursor(r, &v, &query) {
+		params := v.ListParams(r)
+		query.Page, query.PageSize = params.Page, params.PageSize
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	s.logger.Info("Handling GetCars request")
 
-	page, err := strconv.Atoi(pageStr)
+	cars, total, err := s.db.GetCars(query)
 	if err != nil {
+		s.logger.Debug("error while getting cars", "error", err.Error())
 		return err
 	}
 
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil {
+	if query.Keyset {
+		return WriteJSON(w, 200, NewCursorPageResponse(w, r, cars, query))
+	}
+	return WriteJSON(w, 200, NewPageResponse(w, r, cars, query.Page, query.PageSize, total))
+}
+
+// @Summary      GetCarStatsHandler
+// @Description  Get fleet statistics: counts by mark, model, year and decade, the age distribution,
+// @Description  the number of owners with more than one car and the top marks. Accepts the same
+// @Description  filters as /cars/get, applied before aggregating.
+// @Tags         cars
+// @Accept       json
+// @Produce      json
+// @Param        top query int false "Number of top marks (default 5, at most 100)"
+// @Param        make query string false "Car mark (alias of mark)"
+// @Param        mark query string false "Car mark"
+// @Param        marks query string false "Comma-separated list of car marks"
+// @Param        model query string false "Car model"
+// @Param        year query int false "Car year"
+// @Param        year_from query int false "Minimum car year"
+// @Param        year_to query int false "Maximum car year"
+// @Param        mark_prefix query string false "Car mark prefix"
+// @Param        mark_contains query string false "Car mark substring"
+// @Param        model_prefix query string false "Car model prefix"
+// @Param        model_contains query string false "Car model substring"
+// @Param        regNum_prefix query string false "Registration number prefix"
+// @Param        regNum_contains query string false "Registration number substring"
+// @Param        owner_name query string false "Owner name prefix"
+// @Param        owner_surname query string false "Owner surname prefix"
+// @Param        owner_patronymic query string false "Owner patronymic prefix"
+// @Success      200 {object} CarStats "Statistics of the matching cars"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /cars/stats [get]
+func (s *Server) GetCarStatsHandler(w http.ResponseWriter, r *http.Request) error {
+	var v Validator
+	filter := ParseCarFilter(r, &v)
+	top := v.QueryInt(r, "top", false, 5)
+	v.Check("top", top, "min=1,max=100")
+	if err := v.Err(); err != nil {
 		return err
 	}
 
-	make := r.URL.Query().Get("make")
-	model := r.URL.Query().Get("model")
-	yearStr := r.URL.Query().Get("year")
-	var year int
-	if yearStr != "" {
-		year, err = strconv.Atoi(yearStr)
-		if err != nil {
-			return err
-		}
+	s.logger.Info("Handling GetCarStats request")
+
+	stats, err := s.db.GetCarStats(filter, top)
+	if err != nil {
+		s.logger.Debug("error while getting car stats", "error", err.Error())
+		return err
 	}
+	return WriteJSON(w, 200, stats)
+}
 
-	s.logger.Info("Handling GetCars request")
+// @Summary      GetCarHandler
+// @Description  Get a car by ID
+// @Tags         cars
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Car ID"
+// @Success      200 {object} Car "The car"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /cars/{id} [get]
+func (s *Server) GetCarHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("Handling GetCar request for ID: %v", id))
+	car, err := s.db.GetCarByID(id)
+	if err != nil {
+		s.logger.Debug("get car error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, car)
+}
 
-	cars, err := s.db.GetCars(page, pageSize, make, model, year)
+// @Summary      GetCarByRegNumHandler
+// @Description  Get a car by registration number
+// @Tags         cars
+// @Accept       json
+// @Produce      json
+// @Param        regNum path string true "Registration number"
+// @Success      200 {object} Car "The car"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /cars/by-reg/{regNum} [get]
+func (s *Server) GetCarByRegNumHandler(w http.ResponseWriter, r *http.Request) error {
+	regNum := mux.Vars(r)["regNum"]
+	s.logger.Debug(fmt.Sprintf("Handling GetCarByRegNum request for regNum: %v", regNum))
+	car, err := s.db.GetCarByRegNum(regNum)
 	if err != nil {
-		s.logger.Debug("error while getting cars", "error", err.Error())
+		s.logger.Debug("get car error", "error", err.Error())
 		return err
 	}
+	return WriteJSON(w, 200, car)
+}
 
-	return WriteJSON(w, 200, cars)
+// @Summary      SearchCarsHandler
+// @Description  Search cars by free text over mark, model, registration number and owner name. Matching tolerates typos; results are ordered by relevance
+// @Tags         cars
+// @Accept       json
+// @Produce      json
+// @Param        q query string true "Search text"
+// @Param        limit query int false "Maximum number of results (default 20, max 100)"
+// @Success      200 {array} SearchResult "Matching cars, best first"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /cars/search [get]
+func (s *Server) SearchCarsHandler(w http.ResponseWriter, r *http.Request) error {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	s.logger.Debug(fmt.Sprintf("Handling SearchCars request for query: %v", text))
+	var v Validator
+	v.Check("q", text, "required,max=255")
+	limit := v.QueryInt(r, "limit", false, 20)
+	v.Check("limit", limit, "min=1,max=100")
+	if err := v.Err(); err != nil {
+		return err
+	}
+	results, err := s.db.SearchCars(text, limit)
+	if err != nil {
+		s.logger.Debug("search cars error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, results)
 }
 
 // @Summary      DeleteCarHandler
@@ -76,11 +221,12 @@ func (s *Server) GetCarsHandler(w http.ResponseWriter, r *http.Request) error {
 // @Param        id path int true "Car ID"
 // @Success      200 {integer} integer "ID of the deleted car"
 // @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
 // @Failure      404 {object} APIError "Resource not found"
 // @Failure      500 {object} APIError "Internal server error"
 // @Router       /cars/delete/{id} [delete]
 func (s *Server) DeleteCarHandler(w http.ResponseWriter, r *http.Request) error {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, err := pathID(r)
 	if err != nil {
 		return err
 	}
@@ -95,96 +241,238 @@ func (s *Server) DeleteCarHandler(w http.ResponseWriter, r *http.Request) error
 }
 
 // @Summary      UpdateCarHandler
-// @Description  Update a car by ID
+// @Description  Partially update a car by ID. Accepts a JSON Merge Patch (RFC 7396), where only the supplied
+// @Description  fields of the car and its owner change, or a JSON Patch (RFC 6902) operation list applied atomically.
+// @Description  Changed fields of the current owner are stored on that person and so apply to every car they own;
+// @Description  setting owner.id to another person, or to 0 with a full name, replaces the owner instead.
 // @Tags         cars
-// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
 // @Produce      json
 // @Param        id path int true "Car ID"
-// @Success      200 {integer} integer "ID of the updated car"
+// @Param        patch body Car true "Merge patch or JSON Patch operations"
+// @Success      200 {object} Car "The updated car"
 // @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
 // @Failure      404 {object} APIError "Resource not found"
+// @Failure      409 {object} APIError "A JSON Patch test operation failed"
+// @Failure      415 {object} APIError "Unsupported patch format"
 // @Failure      500 {object} APIError "Internal server error"
-// @Router       /cars/update/{id} [put]
+// @Router       /cars/{id} [patch]
 func (s *Server) UpdateCarHandler(w http.ResponseWriter, r *http.Request) error {
-	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	id, err := pathID(r)
 	if err != nil {
 		return err
 	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(err, "reading request body failed")
+	}
+	apply, err := requestPatch(r, body)
+	if err == errUnsupportedPatch {
+		return unsupportedPatch(w)
+	}
+	if err != nil {
+		return err
+	}
+
 	s.logger.Debug(fmt.Sprintf("Handling UpdateCar request for ID: %v", id))
-	updatedCar := Car{}
-	err = s.db.UpdateCarByID(id, &updatedCar)
+	err = s.db.ModifyCarByID(id, func(car *Car) error {
+		if err := patchCar(car, apply); err != nil {
+			return err
+		}
+		return Validate(car)
+	})
 	if err != nil {
 		s.logger.Debug("update car error", "error", err.Error())
 		return err
 	}
-	return WriteJSON(w, 200, id)
+	car, err := s.db.GetCarByID(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, 200, car)
+}
+
+// @Summary      ReplaceCarHandler
+// @Description  Replace a car by ID. Fields missing from the body are cleared.
+// @Tags         cars
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Car ID"
+// @Param        car body Car true "New car state"
+// @Success      200 {object} Car "The updated car"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /cars/{id} [put]
+func (s *Server) ReplaceCarHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(err, "reading request body failed")
+	}
+	car := new(Car)
+	if err := json.Unmarshal(body, car); err != nil {
+		return BadRequestError(err, "invalid request body")
+	}
+	if err := Validate(car); err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("Handling ReplaceCar request for ID: %v", id))
+	return s.replaceCar(w, id, car)
+}
+
+func (s *Server) replaceCar(w http.ResponseWriter, id int, car *Car) error {
+	if err := s.db.UpdateCarByID(id, car); err != nil {
+		s.logger.Debug("update car error", "error", err.Error())
+		return err
+	}
+	updatedCar, err := s.db.GetCarByID(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, 200, updatedCar)
+}
+
+type AddCarsRequest struct {
+	RegNums    []string     `json:"regNums" validate:"min=1,max=1000"`
+	OnConflict ConflictMode `json:"onConflict" enums:"reject,skip,upsert" default:"reject"`
 }
 
 // @Summary      AddCarHandler
-// @Description  Add one or more cars
+// @Description  Add one or more cars. Car info is looked up concurrently for every registration number;
+// @Description  responds with 201 when all lookups succeed and 207 with per-plate results otherwise.
+// @Description  onConflict decides what happens to plates that are already stored: reject fails the request
+// @Description  with 409, skip leaves them untouched and upsert re-enriches and updates them.
 // @Tags         cars
 // @Accept       json
 // @Produce      json
-// @Param        regNums body string true "Registration numbers of cars (comma-separated)"
-// @Success      201 {array} Car "Successful response with an array of added cars"
+// @Param        request body AddCarsRequest true "Registration numbers of cars"
+// @Success      201 {object} AddCarsResponse "All cars were added"
+// @Success      207 {object} AddCarsResponse "Some cars could not be added"
 // @Failure      400 {object} APIError "Bad request"
+// @Failure      409 {object} APIError "Some cars already exist"
+// @Failure      422 {object} APIError "Validation failed"
 // @Failure      500 {object} APIError "Internal server error"
+// @Failure      502 {object} APIError "Car info API failure"
 // @Router       /cars/add [post]
 func (s *Server) AddCarHandler(w http.ResponseWriter, r *http.Request) error {
-	var cars []*Car
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return err
+		return BadRequestError(err, "reading request body failed")
 	}
 
-	var requestData struct {
-		RegNums []string `json:"regNums"`
-	}
+	var requestData AddCarsRequest
 	if err := json.Unmarshal(body, &requestData); err != nil {
+		return BadRequestError(err, "invalid request body")
+	}
+	var v Validator
+	v.Struct("", requestData)
+	switch requestData.OnConflict {
+	case "":
+		requestData.OnConflict = ConflictReject
+	case ConflictReject, ConflictSkip, ConflictUpsert:
+	default:
+		v.Add("onConflict", "invalid_value", "must be one of reject, skip, upsert")
+	}
+	var regNums []string
+	seen := make(map[string]bool, len(requestData.RegNums))
+	for i, regNum := range requestData.RegNums {
+		regNum = NormalizeRegNum(regNum)
+		v.Check(fmt.Sprintf("regNums[%d]", i), regNum, "required,max=20,regnum")
+		if !seen[regNum] {
+			seen[regNum] = true
+			regNums = append(regNums, regNum)
+		}
+	}
+	if err := v.Err(); err != nil {
 		return err
 	}
 
-	s.logger.Info("Handling AddCar request")
-	if err := s.db.AddCars(requestData.RegNums); err != nil {
+	s.logger.Info("Handling AddCar request", "count", len(regNums), "onConflict", requestData.OnConflict)
+	existing, err := s.db.ExistingRegNums(regNums)
+	if err != nil {
 		return err
 	}
-
-	for _, regNum := range requestData.RegNums {
-		carInfo, err := getCarInfo(regNum)
-		if err != nil {
-			s.logger.Debug("Error getting car info", "error", err.Error())
-			return err
+	if len(existing) > 0 && requestData.OnConflict == ConflictReject {
+		conflict := &Error{Kind: KindConflict, Message: "cars already exist: " + strings.Join(existing, ", ")}
+		for _, regNum := range existing {
+			conflict.Fields = append(conflict.Fields, FieldError{Field: "regNums", Code: "duplicate", Message: regNum + " already exists"})
 		}
-		s.logger.Info("Received car info from external API", "car info", carInfo)
-		cars = append(cars, carInfo)
+		return conflict
 	}
 
-	return WriteJSON(w, http.StatusCreated, cars)
-}
-
-// External API call
-func getCarInfo(regNum string) (*Car, error) {
-	apiUrl := "http://external-api.com/info?regNum=" + regNum
-
-	response, err := http.Get(apiUrl)
-	if err != nil {
-		return nil, err
+	resp := &AddCarsResponse{}
+	var toEnrich []string
+	for _, regNum := range regNums {
+		if requestData.OnConflict == ConflictSkip && slices.Contains(existing, regNum) {
+			resp.Results = append(resp.Results, &AddCarResult{RegNum: regNum, Status: AddCarStatusSkipped})
+			resp.Skipped++
+			continue
+		}
+		toEnrich = append(toEnrich, regNum)
 	}
-	defer response.Body.Close()
 
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status code: %d", response.StatusCode)
+	var (
+		cars       []*Car
+		carResults []*AddCarResult
+	)
+	for _, res := range enrichCars(r.Context(), s.provider, toEnrich, s.enrichWorkers) {
+		resp.Results = append(resp.Results, res)
+		if res.Status == AddCarStatusCreated {
+			// IDs in the car info belong to the external API, not to this
+			// database; the owner is matched on its full name instead.
+			res.Car.RegNum, res.Car.ID, res.Car.Owner.ID = res.RegNum, 0, 0
+			if err := Validate(res.Car); err != nil {
+				res.Status, res.Car, res.Error, res.Code = AddCarStatusFailed, nil, err.Error(), KindValidation
+			}
+		}
+		if res.Status != AddCarStatusCreated {
+			s.logger.Debug("Error getting car info", "regNum", res.RegNum, "error", res.Error)
+			resp.Failed++
+			continue
+		}
+		s.logger.Info("Received car info from external API", "car info", res.Car)
+		cars = append(cars, res.Car)
+		carResults = append(carResults, res)
 	}
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
+	if len(cars) > 0 {
+		statuses, err := s.db.AddCars(cars, requestData.OnConflict)
+		if err != nil {
+			s.logger.Debug("error while adding cars", "error", err.Error())
+			return err
+		}
+		for i, status := range statuses {
+			carResults[i].Status = status
+			switch status {
+			case AddCarStatusCreated:
+				resp.Created++
+			case AddCarStatusUpdated:
+				resp.Updated++
+			case AddCarStatusSkipped:
+				carResults[i].Car = nil
+				resp.Skipped++
+			}
+		}
 	}
 
-	var car Car
-	if err := json.Unmarshal(body, &car); err != nil {
-		return nil, err
+	order := make(map[string]int, len(regNums))
+	for i, regNum := range regNums {
+		order[regNum] = i
 	}
+	slices.SortFunc(resp.Results, func(a, b *AddCarResult) int {
+		return order[a.RegNum] - order[b.RegNum]
+	})
 
-	return &car, nil
+	status := http.StatusCreated
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	return WriteJSON(w, status, resp)
 }
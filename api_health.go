@@ -0,0 +1,33 @@
+package main
+
+import "net/http"
+
+type HealthResponse struct {
+	Status      string        `json:"status" example:"ok"`
+	SchemaDrift []SchemaDrift `json:"schemaDrift,omitempty"`
+}
+
+// @Summary      HealthHandler
+// @Description  Report whether the service can serve requests. The database schema is compared
+// @Description  with the columns the store uses; any drift is listed and makes the check fail.
+// @Tags         health
+// @Produce      json
+// @Success      200 {object} HealthResponse "Service is healthy"
+// @Failure      503 {object} HealthResponse "Database schema drifted from what the store expects"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /health [get]
+func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) error {
+	checker, ok := s.db.(schemaChecker)
+	if !ok {
+		return WriteJSON(w, 200, HealthResponse{Status: "ok"})
+	}
+	drift, err := checker.CheckSchema(r.Context())
+	if err != nil {
+		s.logger.Debug("schema check error", "error", err.Error())
+		return newError(KindInternal, err, "checking database schema failed")
+	}
+	if len(drift) > 0 {
+		return WriteJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "schema_drift", SchemaDrift: drift})
+	}
+	return WriteJSON(w, 200, HealthResponse{Status: "ok"})
+}
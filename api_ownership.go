@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type TransferRequest struct {
+	FromOwnerID   int    `json:"fromOwnerId"`
+	ToOwnerID     int    `json:"toOwnerId" validate:"min=1"`
+	EffectiveDate string `json:"effectiveDate" example:"2024-03-01"`
+}
+
+// @Summary      TransferCarHandler
+// @Description  Transfer a car to another owner. The effective date defaults to today and cannot be in the future.
+// @Tags         cars
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Car ID"
+// @Param        transfer body TransferRequest true "Transfer details"
+// @Success      201 {object} Ownership "The new ownership period"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      409 {object} APIError "Car is not owned by the given person"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /cars/{id}/transfer [post]
+func (s *Server) TransferCarHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(err, "reading request body failed")
+	}
+	var requestData TransferRequest
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		return BadRequestError(err, "invalid request body")
+	}
+	if err := Validate(requestData); err != nil {
+		return err
+	}
+
+	transfer := &Transfer{
+		FromOwnerID:   requestData.FromOwnerID,
+		ToOwnerID:     requestData.ToOwnerID,
+		EffectiveDate: time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	if requestData.EffectiveDate != "" {
+		transfer.EffectiveDate, err = time.Parse(time.DateOnly, requestData.EffectiveDate)
+		if err != nil {
+			return FieldValidationError(err, "effectiveDate", "invalid_date", "invalid effectiveDate")
+		}
+	}
+
+	s.logger.Debug(fmt.Sprintf("Handling TransferCar request for ID: %v", id))
+	ownership, err := s.db.TransferCar(id, transfer)
+	if err != nil {
+		s.logger.Debug("car transfer error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, http.StatusCreated, ownership)
+}
+
+// @Summary      GetCarOwnersHandler
+// @Description  Get the ownership timeline of a car, oldest first
+// @Tags         cars
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Car ID"
+// @Success      200 {array} Ownership "Ownership periods of the car"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /cars/{id}/owners [get]
+func (s *Server) GetCarOwnersHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("Handling GetCarOwners request for ID: %v", id))
+	history, err := s.db.GetCarOwnershipHistory(id)
+	if err != nil {
+		s.logger.Debug("get car owners error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, history)
+}
+
+// @Summary      GetPersonCarsHandler
+// @Description  Get every car a person has ever owned, oldest ownership first
+// @Tags         people
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Person ID"
+// @Success      200 {array} Ownership "Ownership periods of the person"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /people/{id}/cars [get]
+func (s *Server) GetPersonCarsHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("Handling GetPersonCars request for ID: %v", id))
+	history, err := s.db.GetPersonOwnershipHistory(id)
+	if err != nil {
+		s.logger.Debug("get person cars error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, history)
+}
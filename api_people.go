@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// @Summary      GetPeopleHandler
+// @Description  Get a list of car owners with pagination support
+// @Tags         people
+// @Accept       json
+// @Produce      json
+// @Param        page query int true "Page number (at most 1000000)"
+// @Param        page_size query int true "Number of items per page (at most 100)"
+// @Param        name query string false "Owner name"
+// @Param        surname query string false "Owner surname"
+// @Param        patronymic query string false "Owner patronymic"
+// @Success      200 {array} People "Successful response with an array of owners"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /people/get [get]
+func (s *Server) GetPeopleHandler(w http.ResponseWriter, r *http.Request) error {
+	var v Validator
+	params := v.ListParams(r)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	name := r.URL.Query().Get("name")
+	surname := r.URL.Query().Get("surname")
+	patronymic := r.URL.Query().Get("patronymic")
+
+	s.logger.Info("Handling GetPeople request")
+
+	people, err := s.db.GetPeople(params.Page, params.PageSize, name, surname, patronymic)
+	if err != nil {
+		s.logger.Debug("error while getting people", "error", err.Error())
+		return err
+	}
+
+	return WriteJSON(w, 200, people)
+}
+
+// @Summary      GetPersonHandler
+// @Description  Get a car owner by ID
+// @Tags         people
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Person ID"
+// @Success      200 {object} People "The owner"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /people/{id} [get]
+func (s *Server) GetPersonHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("Handling GetPerson request for ID: %v", id))
+	person, err := s.db.GetPersonByID(id)
+	if err != nil {
+		s.logger.Debug("get person error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, person)
+}
+
+// @Summary      AddPersonHandler
+// @Description  Add a car owner
+// @Tags         people
+// @Accept       json
+// @Produce      json
+// @Param        person body People true "Owner to add"
+// @Success      201 {object} People "The created owner"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /people/add [post]
+func (s *Server) AddPersonHandler(w http.ResponseWriter, r *http.Request) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(err, "reading request body failed")
+	}
+	person := new(People)
+	if err := json.Unmarshal(body, person); err != nil {
+		return BadRequestError(err, "invalid request body")
+	}
+	if err := Validate(person); err != nil {
+		return err
+	}
+
+	s.logger.Info("Handling AddPerson request")
+	if err := s.db.AddPerson(person); err != nil {
+		s.logger.Debug("add person error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, http.StatusCreated, person)
+}
+
+// @Summary      ReplacePersonHandler
+// @Description  Replace a car owner by ID. Fields missing from the body are cleared. All cars referencing the owner see the change.
+// @Tags         people
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Person ID"
+// @Param        person body People true "New owner state"
+// @Success      200 {object} People "The updated owner"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /people/{id} [put]
+func (s *Server) ReplacePersonHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(err, "reading request body failed")
+	}
+	person := new(People)
+	if err := json.Unmarshal(body, person); err != nil {
+		return BadRequestError(err, "invalid request body")
+	}
+	if err := Validate(person); err != nil {
+		return err
+	}
+
+	s.logger.Debug(fmt.Sprintf("Handling ReplacePerson request for ID: %v", id))
+	if err := s.db.UpdatePersonByID(id, person); err != nil {
+		s.logger.Debug("replace person error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, person)
+}
+
+// @Summary      PatchPersonHandler
+// @Description  Partially update a car owner by ID with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// @Tags         people
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id path int true "Person ID"
+// @Param        patch body People true "Merge patch or JSON Patch operations"
+// @Success      200 {object} People "The updated owner"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      409 {object} APIError "A JSON Patch test operation failed"
+// @Failure      415 {object} APIError "Unsupported patch format"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /people/{id} [patch]
+func (s *Server) PatchPersonHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return BadRequestError(err, "reading request body failed")
+	}
+	apply, err := requestPatch(r, body)
+	if err == errUnsupportedPatch {
+		return unsupportedPatch(w)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debug(fmt.Sprintf("Handling PatchPerson request for ID: %v", id))
+	var updated People
+	err = s.db.ModifyPersonByID(id, func(person *People) error {
+		if err := patchPerson(person, apply); err != nil {
+			return err
+		}
+		if err := Validate(person); err != nil {
+			return err
+		}
+		updated = *person
+		return nil
+	})
+	if err != nil {
+		s.logger.Debug("patch person error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, updated)
+}
+
+// @Summary      DeletePersonHandler
+// @Description  Delete a car owner by ID. Owners with ownership history cannot be deleted.
+// @Tags         people
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Person ID"
+// @Success      200 {integer} integer "ID of the deleted owner"
+// @Failure      400 {object} APIError "Bad request"
+// @Failure      422 {object} APIError "Validation failed"
+// @Failure      404 {object} APIError "Resource not found"
+// @Failure      409 {object} APIError "Person has ownership history"
+// @Failure      500 {object} APIError "Internal server error"
+// @Router       /people/delete/{id} [delete]
+func (s *Server) DeletePersonHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("Handling DeletePerson request for ID: %v", id))
+	if err := s.db.DeletePersonByID(id); err != nil {
+		s.logger.Debug("person deletion error", "error", err.Error())
+		return err
+	}
+	return WriteJSON(w, 200, id)
+}
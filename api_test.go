@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestServer returns a server on an empty MemoryStore that looks up car
+// info in cars.
+func newTestServer(cars ...Car) (*Server, *MemoryStore) {
+	db := NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewServer(":0", db, NewStaticCarInfoProvider(cars...), logger), db
+}
+
+// serve calls handler with a request of method and body, setting the
+// Content-Type if given and the path variables in vars.
+func serve(handler APIFunc, method, contentType, body string, vars map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	r = mux.SetURLVars(r, vars)
+	w := httptest.NewRecorder()
+	HTTPHandleFunc(handler)(w, r)
+	return w
+}
+
+func TestAddCarIgnoresUpstreamIDs(t *testing.T) {
+	s, db := newTestServer(Car{
+		ID: 42, RegNum: "A123BC77", Mark: "Lada", Model: "Vesta", Year: 2019,
+		Owner: People{ID: 1, Name: "Other", Surname: "Person"},
+	})
+	local := &People{Name: "Ivan", Surname: "Ivanov"}
+	if err := db.AddPerson(local); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(s.AddCarHandler, http.MethodPost, "", `{"regNums":["A123BC77"]}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	car, err := db.GetCarByRegNum("A123BC77")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if car.ID == 42 {
+		t.Errorf("car kept the upstream ID %d", car.ID)
+	}
+	if car.Owner.ID == local.ID || car.Owner.Name != "Other" || car.Owner.Surname != "Person" {
+		t.Errorf("owner = %+v, want a new person Other Person", car.Owner)
+	}
+	if person, err := db.GetPersonByID(local.ID); err != nil || *person != *local {
+		t.Errorf("unrelated person = %+v, %v, want it unchanged", person, err)
+	}
+}
+
+func TestGetPeopleEmptyPage(t *testing.T) {
+	s, _ := newTestServer()
+	r := httptest.NewRequest(http.MethodGet, "/people/get?page=1&page_size=10", nil)
+	w := httptest.NewRecorder()
+	HTTPHandleFunc(s.GetPeopleHandler)(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Errorf("body = %s, want []", body)
+	}
+}
+
+func TestUpdateCarEditsOwner(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		patch          string
+		wantStatus     int
+		wantPatronymic string
+	}{
+		{"merge patch", mergePatchMediaType, `{"owner":{"patronymic":"Petrovich"}}`, http.StatusOK, "Petrovich"},
+		{"JSON Patch", jsonPatchMediaType, `[{"op":"remove","path":"/owner/patronymic"}]`, http.StatusOK, ""},
+		{"invalid owner", mergePatchMediaType, `{"owner":{"name":""}}`, http.StatusUnprocessableEntity, "Ivanovich"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db := newTestServer()
+			owner := People{Name: "Ivan", Surname: "Ivanov", Patronymic: "Ivanovich"}
+			cars := []*Car{
+				{RegNum: "A123BC77", Mark: "Lada", Model: "Vesta", Owner: owner},
+				{RegNum: "B456EK77", Mark: "Kia", Model: "Rio", Owner: owner},
+			}
+			if _, err := db.AddCars(cars, ConflictReject); err != nil {
+				t.Fatal(err)
+			}
+			ownerID := cars[0].Owner.ID
+
+			w := serve(s.UpdateCarHandler, http.MethodPatch, tt.contentType, tt.patch, map[string]string{"id": "1"})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			for _, car := range cars {
+				stored, err := db.GetCarByID(car.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := People{ID: ownerID, Name: "Ivan", Surname: "Ivanov", Patronymic: tt.wantPatronymic}
+				if stored.Owner != want {
+					t.Errorf("car %d: owner = %+v, want %+v", car.ID, stored.Owner, want)
+				}
+			}
+			people, err := db.GetPeople(1, 10, "", "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(people) != 1 {
+				t.Errorf("%d people stored, want the owner only", len(people))
+			}
+			history, err := db.GetCarOwnershipHistory(cars[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 1 {
+				t.Errorf("car has %d ownership periods, want 1", len(history))
+			}
+		})
+	}
+}
@@ -1,15 +1,67 @@
 package main
 
+import "time"
+
+// The `validate` tags are checked by Validator; string limits match the
+// VARCHAR sizes of the tables.
 type People struct {
-	Name       string `json:"name"`
-	Surname    string `json:"surname"`
-	Patronymic string `json:"patronymic"`
+	ID         int    `json:"id"`
+	Name       string `json:"name" validate:"required,max=255"`
+	Surname    string `json:"surname" validate:"required,max=255"`
+	Patronymic string `json:"patronymic" validate:"max=255"`
 }
 
 type Car struct {
-	RegNum string `json:"regNum"`
-	Mark   string `json:"mark"`
-	Model  string `json:"model"`
-	Year   int    `json:"year"`
-	Owner  People `json:"owner"`
+	ID     int    `json:"id"`
+	RegNum string `json:"regNum" validate:"required,max=20,regnum"`
+	Mark   string `json:"mark" validate:"required,max=255"`
+	Model  string `json:"model" validate:"required,max=255"`
+	Year   int    `json:"year" validate:"year"`
+	Owner  People `json:"owner" validate:"omitempty,ref"`
+}
+
+// Ownership is a period during which a person owned a car. Until is nil
+// for the current owner.
+type Ownership struct {
+	ID    int        `json:"id"`
+	CarID int        `json:"carId"`
+	Car   *Car       `json:"car,omitempty"`
+	Owner *People    `json:"owner,omitempty"`
+	Since time.Time  `json:"since"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+type Transfer struct {
+	FromOwnerID   int
+	ToOwnerID     int
+	EffectiveDate time.Time
+}
+
+// ConflictMode decides what adding a car does when its registration number
+// is already stored.
+type ConflictMode string
+
+const (
+	// ConflictReject fails the whole request.
+	ConflictReject ConflictMode = "reject"
+	// ConflictSkip leaves the stored car untouched.
+	ConflictSkip ConflictMode = "skip"
+	// ConflictUpsert overwrites the stored car with the new data.
+	ConflictUpsert ConflictMode = "upsert"
+)
+
+type AddCarStatus string
+
+const (
+	AddCarStatusCreated AddCarStatus = "created"
+	AddCarStatusUpdated AddCarStatus = "updated"
+	AddCarStatusSkipped AddCarStatus = "skipped"
+	AddCarStatusFailed  AddCarStatus = "failed"
+)
+
+// SearchResult is a car matched by free-text search. Higher scores are
+// better matches.
+type SearchResult struct {
+	Car   *Car    `json:"car"`
+	Score float64 `json:"score"`
 }
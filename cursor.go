@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// carCursor is the decoded form of the opaque cursor of keyset pagination.
+// It records the sort order and the sort key values of the last car
+// returned, so the next page starts right after it.
+type carCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// CursorPageResponse is one page of a keyset-paginated list.
+type CursorPageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
+
+func sortString(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// carSortValue returns the value of car that the sort field orders by,
+// matching the expressions in carSortColumns.
+func carSortValue(car *Car, field string) any {
+	switch field {
+	case "id":
+		return car.ID
+	case "year":
+		return car.Year
+	case "mark":
+		return car.Mark
+	case "regNum":
+		return car.RegNum
+	case "owner.surname":
+		return car.Owner.Surname
+	}
+	panic("cursor: unknown sort field " + field)
+}
+
+// encodeCarCursor returns the cursor pointing after car.
+func encodeCarCursor(car *Car, keys []SortKey) string {
+	c := carCursor{Sort: sortString(keys)}
+	for _, key := range keys {
+		value, _ := json.Marshal(carSortValue(car, key.Field))
+		c.Values = append(c.Values, value)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCarCursor parses cursor and returns its sort order and the typed
+// values to continue after.
+func decodeCarCursor(cursor string) ([]SortKey, []any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	var c carCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, nil, err
+	}
+	var keys []SortKey
+	for _, field := range strings.Split(c.Sort, ",") {
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if _, ok := carSortColumns[key.Field]; !ok {
+			return nil, nil, fmt.Errorf("unknown sort field %q", key.Field)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) != len(c.Values) {
+		return nil, nil, fmt.Errorf("cursor has %d values for %d sort keys", len(c.Values), len(keys))
+	}
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		switch key.Field {
+		case "id", "year":
+			var n int
+			err = json.Unmarshal(c.Values[i], &n)
+			values[i] = n
+		default:
+			var s string
+			err = json.Unmarshal(c.Values[i], &s)
+			values[i] = s
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return keys, values, nil
+}
+
+// ParseCarCursor reads the cursor and limit parameters of keyset
+// pagination into q, recording violations in v. It reports whether keyset
+// pagination was requested. The sort order is taken from the cursor; an
+// explicit sort parameter must match it.
+func ParseCarCursor(r *http.Request, v *Validator, q *CarQuery) bool {
+	query := r.URL.Query()
+	if !query.Has("cursor") && !query.Has("limit") {
+		return false
+	}
+	q.Keyset = true
+	q.Page = 1
+	q.PageSize = v.QueryInt(r, "limit", false, 20)
+	v.Check("limit", q.PageSize, "min=1,max=100")
+
+	cursor := query.Get("cursor")
+	if cursor == "" {
+		return true
+	}
+	keys, values, err := decodeCarCursor(cursor)
+	if err != nil {
+		v.Add("cursor", "invalid_cursor", "is malformed")
+		return true
+	}
+	if query.Get("sort") != "" && sortString(q.Sort) != sortString(keys) {
+		v.Add("cursor", "sort_mismatch", "was issued for sort %q", sortString(keys))
+		return true
+	}
+	q.Sort = keys
+	q.After = values
+	return true
+}
+
+// keysetCondition returns a condition selecting the rows that sort after
+// values, expanding mixed sort directions into
+// (k1 > v1) OR (k1 = v1 AND k2 < v2) OR ...
+func keysetCondition(keys []SortKey, values []any) (string, []any) {
+	var (
+		terms []string
+		args  []any
+	)
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, carSortColumns[keys[j].Field]+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if key.Desc {
+			op = " < ?"
+		}
+		parts = append(parts, carSortColumns[key.Field]+op)
+		args = append(args, values[i])
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// NewCursorPageResponse builds the envelope for a keyset page. cars holds
+// up to one extra car, fetched to learn whether another page exists.
+func NewCursorPageResponse(w http.ResponseWriter, r *http.Request, cars []*Car, q CarQuery) *CursorPageResponse[*Car] {
+	resp := &CursorPageResponse[*Car]{Items: cars, Limit: q.PageSize}
+	if len(cars) > q.PageSize {
+		resp.Items = cars[:q.PageSize]
+		resp.NextCursor = encodeCarCursor(resp.Items[len(resp.Items)-1], q.Sort)
+
+		query := r.URL.Query()
+		query.Set("cursor", resp.NextCursor)
+		query.Set("limit", strconv.Itoa(q.PageSize))
+		query.Del("page")
+		next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		resp.Next = next.String()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, resp.Next))
+	}
+	if resp.Items == nil {
+		resp.Items = []*Car{}
+	}
+	return resp
+}
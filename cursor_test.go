@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// rawCursor encodes a cursor payload as is.
+func rawCursor(payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(payload))
+}
+
+func TestCarCursorRoundTrip(t *testing.T) {
+	car := &Car{ID: 7, RegNum: "A123BC77", Mark: "Lada", Year: 2019, Owner: People{Surname: "Иванов"}}
+	keys := []SortKey{{Field: "year", Desc: true}, {Field: "owner.surname"}, {Field: "mark"}, {Field: "regNum"}, {Field: "id"}}
+
+	gotKeys, values, err := decodeCarCursor(encodeCarCursor(car, keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotKeys, keys) {
+		t.Errorf("keys = %v, want %v", gotKeys, keys)
+	}
+	if want := []any{2019, "Иванов", "Lada", "A123BC77", 7}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %#v, want %#v", values, want)
+	}
+}
+
+func TestDecodeCarCursorErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"not JSON", rawCursor("year")},
+		{"unknown sort field", rawCursor(`{"s":"owner.name,id","v":["Ivan",1]}`)},
+		{"missing value", rawCursor(`{"s":"-year,id","v":[2019]}`)},
+		{"extra value", rawCursor(`{"s":"id","v":[1,2]}`)},
+		{"wrong value type", rawCursor(`{"s":"year,id","v":["2019",1]}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCarCursor(tt.cursor); err == nil {
+				t.Error("cursor was accepted")
+			}
+		})
+	}
+}
+
+func TestParseCarCursor(t *testing.T) {
+	byYear := encodeCarCursor(&Car{ID: 3, Year: 2019}, []SortKey{{Field: "year", Desc: true}, {Field: "id"}})
+	tests := []struct {
+		name      string
+		query     string
+		keyset    bool
+		pageSize  int
+		sort      string
+		after     []any
+		fieldCode string
+	}{
+		{"page mode", "page=2&page_size=10", false, 0, "id", nil, ""},
+		{"first keyset page", "limit=5", true, 5, "id", nil, ""},
+		{"default limit", "cursor=", true, 20, "id", nil, ""},
+		{"cursor sets the sort", "cursor=" + byYear, true, 20, "-year,id", []any{2019, 3}, ""},
+		{"matching sort", "sort=-year&cursor=" + byYear, true, 20, "-year,id", []any{2019, 3}, ""},
+		{"sort mismatch", "sort=year&cursor=" + byYear, true, 20, "year,id", nil, "sort_mismatch"},
+		{"malformed cursor", "cursor=!!!", true, 20, "id", nil, "invalid_cursor"},
+		{"unknown sort field", "cursor=" + rawCursor(`{"s":"color","v":["red"]}`), true, 20, "id", nil, "invalid_cursor"},
+		{"value count mismatch", "cursor=" + rawCursor(`{"s":"-year,id","v":[2019]}`), true, 20, "id", nil, "invalid_cursor"},
+		{"limit too large", "limit=101", true, 101, "id", nil, "too_large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/cars/get?"+tt.query, nil)
+			var v Validator
+			q := CarQuery{Sort: ParseCarSort(r, &v)}
+			keyset := ParseCarCursor(r, &v, &q)
+
+			if keyset != tt.keyset || q.Keyset != tt.keyset {
+				t.Errorf("keyset = %v, want %v", keyset, tt.keyset)
+			}
+			if q.PageSize != tt.pageSize {
+				t.Errorf("page size = %d, want %d", q.PageSize, tt.pageSize)
+			}
+			if got := sortString(q.Sort); got != tt.sort {
+				t.Errorf("sort = %s, want %s", got, tt.sort)
+			}
+			if !reflect.DeepEqual(q.After, tt.after) {
+				t.Errorf("after = %#v, want %#v", q.After, tt.after)
+			}
+			var codes []string
+			for _, f := range v.fields {
+				codes = append(codes, f.Code)
+			}
+			if tt.fieldCode == "" && len(codes) > 0 || tt.fieldCode != "" && !reflect.DeepEqual(codes, []string{tt.fieldCode}) {
+				t.Errorf("violations = %v, want %q", codes, tt.fieldCode)
+			}
+		})
+	}
+}
@@ -1,24 +1,125 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"strconv"
-	"strings"
-
-	_ "github.com/lib/pq"
+	"time"
 )
 
 type Database interface {
-	GetCars(page int, pageSize int, make, model string, year int) ([]*Car, error)
+	GetCars(q CarQuery) ([]*Car, int, error)
+	GetCarStats(filter CarFilter, top int) (*CarStats, error)
+	GetCarByID(id int) (*Car, error)
+	GetCarByRegNum(regNum string) (*Car, error)
+	SearchCars(text string, limit int) ([]*SearchResult, error)
 	DeleteCarByID(id int) error
 	UpdateCarByID(id int, car *Car) error
-	AddCars(regNums []string) error
+	ModifyCarByID(id int, modify func(car *Car) error) error
+	AddCars(cars []*Car, onConflict ConflictMode) ([]AddCarStatus, error)
+	ExistingRegNums(regNums []string) ([]string, error)
+	GetPeople(page int, pageSize int, name, surname, patronymic string) ([]*People, error)
+	GetPersonByID(id int) (*People, error)
+	AddPerson(person *People) error
+	UpdatePersonByID(id int, person *People) error
+	ModifyPersonByID(id int, modify func(person *People) error) error
+	DeletePersonByID(id int) error
+	TransferCar(carID int, transfer *Transfer) (*Ownership, error)
+	GetCarOwnershipHistory(carID int) ([]*Ownership, error)
+	GetPersonOwnershipHistory(personID int) ([]*Ownership, error)
 }
 
-type PostgresStore struct {
+// NewStore opens the Database selected by DB_DRIVER: "postgres", the
+// default, "sqlite" or "memory". Pending migrations are applied unless
+// DB_AUTO_MIGRATE is false.
+func NewStore(ctx context.Context) (Database, error) {
+	store, err := openStore()
+	if err != nil {
+		return nil, err
+	}
+	if auto, ok := os.LookupEnv("DB_AUTO_MIGRATE"); ok && auto == "false" {
+		return store, nil
+	}
+	if store, ok := store.(migratable); ok {
+		m, err := store.Migrator()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := m.Up(ctx); err != nil {
+			return nil, fmt.Errorf("migrating database: %w", err)
+		}
+	}
+	return store, nil
+}
+
+// migratable is a Database with a schema managed by migrations.
+type migratable interface {
+	Migrator() (*Migrator, error)
+}
+
+// openStore opens the Database selected by DB_DRIVER as is.
+func openStore() (Database, error) {
+	driver, _ := os.LookupEnv("DB_DRIVER")
+	switch driver {
+	case "", "postgres":
+		store, err := NewPostgresStore()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "sqlite":
+		store, err := NewSQLiteStore()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "memory":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown DB_DRIVER %q", driver)
+}
+
+// sqlStore implements Database on database/sql for PostgresStore and
+// SQLiteStore. Both drivers bind $1, $2 and so on by number, so the
+// statements are shared; the stores only add what their dialects do
+// differently.
+type sqlStore struct {
 	db *sql.DB
+	// lockRows makes transactions lock the rows they read before changing
+	// them. SQLite has no row locks and lets one writer in at a time.
+	lockRows bool
+}
+
+// forUpdate returns the clause locking the selected rows of table, or
+// nothing if the store does not lock rows.
+func (s *sqlStore) forUpdate(table string) string {
+	if !s.lockRows {
+		return ""
+	}
+	return " FOR UPDATE OF " + table
+}
+
+// inTx runs f in a transaction that is committed if f succeeds. Errors are
+// translated with storeError.
+func (s *sqlStore) inTx(opts *sql.TxOptions, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(context.Background(), opts)
+	if err != nil {
+		return storeError(err)
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return storeError(err)
+	}
+	return storeError(tx.Commit())
+}
+
+// snapshot is the transaction of reads that must see one consistent state.
+var snapshot = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+type PostgresStore struct {
+	sqlStore
 }
 
 func NewPostgresStore() (*PostgresStore, error) {
@@ -38,143 +139,318 @@ func NewPostgresStore() (*PostgresStore, error) {
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
-	store := &PostgresStore{db: db}
-	err = initTables(store)
-	if err != nil {
-		return nil, err
-	}
-	return store, nil
+	return newPostgresStore(db), nil
 }
 
-func initTables(s *PostgresStore) error {
-	peopleQuery := `CREATE TABLE IF NOT EXISTS people (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    surname VARCHAR(255) NOT NULL,
-    patronymic VARCHAR(255)
-);`
-	_, err := s.db.Query(peopleQuery)
-	if err != nil {
-		return err
-	}
-	carQuery := `CREATE TABLE IF NOT EXISTS cars (
-    id SERIAL PRIMARY KEY,
-    regNum VARCHAR(20) NOT NULL,
-    mark VARCHAR(255) NOT NULL,
-    model VARCHAR(255) NOT NULL,
-    year INTEGER,
-    owner_id INTEGER REFERENCES people(id)
-);
-  `
-	_, err = s.db.Query(carQuery)
-	return err
+func newPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{sqlStore{db: db, lockRows: true}}
 }
 
-func (s *PostgresStore) GetCars(page int, pageSize int, make string, model string, year int) ([]*Car, error) {
-	var cars []*Car
-	offset := (page - 1) * pageSize
-
-	query := "SELECT * FROM cars"
-
-	conditions := []string{}
-	if make != "" {
-		conditions = append(conditions, fmt.Sprintf("make = '%s'", make))
-	}
-	if model != "" {
-		conditions = append(conditions, fmt.Sprintf("model = '%s'", model))
-	}
-	if year != 0 {
-		conditions = append(conditions, fmt.Sprintf("year = %d", year))
-	}
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
-	}
-
-	query += fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize, offset)
+// GetCars returns the requested page of cars and the number of cars
+// matching the filter. Both queries run in one read-only snapshot so the
+// total is consistent with the page. Keyset queries skip the count.
+func (s *sqlStore) GetCars(q CarQuery) (cars []*Car, total int, err error) {
+	err = s.inTx(snapshot, func(tx *sql.Tx) error {
+		filtered := q.Filter.apply(newQuery(selectCarQuery))
+		if q.Keyset {
+			if q.After != nil {
+				cond, args := keysetCondition(q.Sort, q.After)
+				filtered.Where(cond, args...)
+			}
+			filtered.Paginate(1, q.PageSize+1)
+		} else {
+			countQuery, countArgs := q.Filter.apply(newQuery("SELECT COUNT(*)" + carFrom)).Build()
+			if err := tx.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
+				return err
+			}
+			filtered.Paginate(q.Page, q.PageSize)
+		}
+		query, args := filtered.OrderBy(carOrderBy(q.Sort)...).Build()
+		cars, err = queryCars(tx, query, args)
+		return err
+	})
+	return cars, total, err
+}
 
-	rows, err := s.db.Query(query)
+// queryCars runs a query selecting carColumns.
+func queryCars(tx *sql.Tx, query string, args []any) ([]*Car, error) {
+	rows, err := tx.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	var cars []*Car
 	for rows.Next() {
-		car, err := ScanIntoCar(rows)
+		car, err := scanCarRow(rows)
 		if err != nil {
 			return nil, err
 		}
 		cars = append(cars, car)
 	}
-	return cars, nil
+	return cars, rows.Err()
+}
+
+const (
+	carColumns = `c.id, c.reg_num, c.mark, c.model, COALESCE(c.year, 0),
+            COALESCE(p.id, 0), COALESCE(p.name, ''), COALESCE(p.surname, ''), COALESCE(p.patronymic, '')`
+	carFrom        = ` FROM cars c LEFT JOIN people p ON p.id = c.owner_id`
+	selectCarQuery = `SELECT ` + carColumns + carFrom
+)
+
+func (s *sqlStore) GetCarByID(id int) (*Car, error) {
+	car, err := scanCarRow(s.db.QueryRow(selectCarQuery+" WHERE c.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, NotFoundError("car %d not found", id)
+	}
+	return car, storeError(err)
+}
+
+func (s *sqlStore) GetCarByRegNum(regNum string) (*Car, error) {
+	regNum = NormalizeRegNum(regNum)
+	car, err := scanCarRow(s.db.QueryRow(selectCarQuery+" WHERE c.reg_num = $1 ORDER BY c.id LIMIT 1", regNum))
+	if err == sql.ErrNoRows {
+		return nil, NotFoundError("car %s not found", regNum)
+	}
+	return car, storeError(err)
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanCarRow maps a row selecting the columns of selectCarQuery to a Car
+// with its owner.
+func scanCarRow(row rowScanner) (*Car, error) {
+	car := new(Car)
+	err := row.Scan(carDest(car)...)
+	return car, err
+}
+
+// carDest returns the scan destinations for carColumns.
+func carDest(car *Car) []any {
+	return []any{
+		&car.ID,
+		&car.RegNum,
+		&car.Mark,
+		&car.Model,
+		&car.Year,
+		&car.Owner.ID,
+		&car.Owner.Name,
+		&car.Owner.Surname,
+		&car.Owner.Patronymic,
+	}
 }
 
-func (s *PostgresStore) DeleteCarByID(id int) error {
-	_, err := s.db.Query(`DELETE FROM cars WHERE id=$1`, id)
+func (s *sqlStore) DeleteCarByID(id int) error {
+	res, err := s.db.Exec(`DELETE FROM cars WHERE id = $1`, id)
 	if err != nil {
-		return err
+		return storeError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return storeError(err)
+	}
+	if n == 0 {
+		return NotFoundError("car %d not found", id)
 	}
 	return nil
 }
 
-func (s *PostgresStore) UpdateCarByID(id int, car *Car) error {
+// UpdateCarByID overwrites the car and records an ownership change
+// effective today if the owner differs from the current one. An owner
+// without an ID is matched on its full name like in AddCars.
+func (s *sqlStore) UpdateCarByID(id int, car *Car) error {
+	return s.inTx(nil, func(tx *sql.Tx) error {
+		var currentOwnerID sql.NullInt64
+		err := tx.QueryRow(`SELECT owner_id FROM cars WHERE id = $1`+s.forUpdate("cars"), id).Scan(&currentOwnerID)
+		if err == sql.ErrNoRows {
+			return NotFoundError("car %d not found", id)
+		}
+		if err != nil {
+			return err
+		}
+		return updateCar(tx, id, car, currentOwnerID)
+	})
+}
+
+// ModifyCarByID loads the car, lets modify change it and stores the result
+// within one transaction, so concurrent updates cannot interleave. Changed
+// fields of the current owner are stored on the person and so apply to
+// every car of that owner.
+func (s *sqlStore) ModifyCarByID(id int, modify func(car *Car) error) error {
+	return s.inTx(nil, func(tx *sql.Tx) error {
+		car, err := scanCarRow(tx.QueryRow(selectCarQuery+" WHERE c.id = $1"+s.forUpdate("c"), id))
+		if err == sql.ErrNoRows {
+			return NotFoundError("car %d not found", id)
+		}
+		if err != nil {
+			return err
+		}
+		owner := car.Owner
+		currentOwnerID := sql.NullInt64{Int64: int64(owner.ID), Valid: owner.ID != 0}
+		if err := modify(car); err != nil {
+			return err
+		}
+		if owner.ID != 0 && car.Owner.ID == owner.ID && car.Owner != owner {
+			if err := updatePerson(tx, owner.ID, &car.Owner); err != nil {
+				return err
+			}
+		}
+		return updateCar(tx, id, car, currentOwnerID)
+	})
+}
+
+func updateCar(tx *sql.Tx, id int, car *Car, currentOwnerID sql.NullInt64) (err error) {
+	car.RegNum = NormalizeRegNum(car.RegNum)
+	if car.Owner.ID == 0 && car.Owner.Name != "" {
+		if car.Owner.ID, err = upsertOwner(tx, &car.Owner); err != nil {
+			return err
+		}
+	}
+
 	query := `
         UPDATE cars
-        SET reg_num = $1, mark = $2, model = $3, year = $4, owner_name = $5, owner_surname = $6, owner_patronymic = $7
-        WHERE id = $8
+        SET reg_num = $1, mark = $2, model = $3, year = NULLIF($4, 0), owner_id = NULLIF($5, 0)
+        WHERE id = $6
     `
-	_, err := s.db.Exec(
+	_, err = tx.Exec(
 		query,
 		car.RegNum,
 		car.Mark,
 		car.Model,
 		car.Year,
-		car.Owner.Name,
-		car.Owner.Surname,
-		car.Owner.Patronymic,
+		car.Owner.ID,
 		id,
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	ownerID := sql.NullInt64{Int64: int64(car.Owner.ID), Valid: car.Owner.ID != 0}
+	if ownerID != currentOwnerID {
+		err = recordOwnerChange(tx, id, ownerID, time.Now())
+	}
+	return err
 }
 
-func (s *PostgresStore) AddCars(regNums []string) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
+// AddCars stores the cars together with their owners in a single
+// transaction. An owner with an ID is referenced as is; otherwise it is
+// matched on its full name and reused if present. onConflict decides what
+// happens to cars whose registration number is already stored; the
+// returned statuses are in the order of cars.
+func (s *sqlStore) AddCars(cars []*Car, onConflict ConflictMode) (statuses []AddCarStatus, err error) {
+	err = s.inTx(nil, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare("INSERT INTO cars (reg_num, mark, model, year, owner_id) VALUES ($1, $2, $3, NULLIF($4, 0), $5) RETURNING id")
 		if err != nil {
-			tx.Rollback()
-			return
+			return err
 		}
-		tx.Commit()
-	}()
+		defer stmt.Close()
+
+		statuses = make([]AddCarStatus, len(cars))
+		for i, car := range cars {
+			car.RegNum = NormalizeRegNum(car.RegNum)
 
-	stmt, err := tx.Prepare("INSERT INTO cars (reg_num) VALUES ($1)")
+			var (
+				carID          int
+				currentOwnerID sql.NullInt64
+			)
+			err := tx.QueryRow(
+				`SELECT id, owner_id FROM cars WHERE reg_num = $1`+s.forUpdate("cars"), car.RegNum,
+			).Scan(&carID, &currentOwnerID)
+			switch {
+			case err == sql.ErrNoRows:
+			case err != nil:
+				return err
+			case onConflict == ConflictSkip:
+				statuses[i] = AddCarStatusSkipped
+				continue
+			case onConflict == ConflictUpsert:
+				if err := updateCar(tx, carID, car, currentOwnerID); err != nil {
+					return err
+				}
+				car.ID = carID
+				statuses[i] = AddCarStatusUpdated
+				continue
+			default:
+				return ConflictError("car %s already exists", car.RegNum)
+			}
+
+			var ownerID sql.NullInt64
+			switch {
+			case car.Owner.ID != 0:
+				ownerID = sql.NullInt64{Int64: int64(car.Owner.ID), Valid: true}
+			case car.Owner.Name != "":
+				id, err := upsertOwner(tx, &car.Owner)
+				if err != nil {
+					return err
+				}
+				car.Owner.ID = id
+				ownerID = sql.NullInt64{Int64: int64(id), Valid: true}
+			}
+			if err := stmt.QueryRow(car.RegNum, car.Mark, car.Model, car.Year, ownerID).Scan(&carID); err != nil {
+				return err
+			}
+			if err := recordOwnerChange(tx, carID, ownerID, time.Now()); err != nil {
+				return err
+			}
+			car.ID = carID
+			statuses[i] = AddCarStatusCreated
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer stmt.Close()
+	return statuses, nil
+}
 
-	for _, regNum := range regNums {
-		_, err := stmt.Exec(regNum)
-		if err != nil {
-			return err
-		}
+// ExistingRegNums returns which of the registration numbers are stored.
+func (s *sqlStore) ExistingRegNums(regNums []string) ([]string, error) {
+	if len(regNums) == 0 {
+		return nil, nil
 	}
+	normalized := make([]any, len(regNums))
+	for i, regNum := range regNums {
+		normalized[i] = NormalizeRegNum(regNum)
+	}
+	query, args := newQuery("SELECT reg_num FROM cars").
+		Where("reg_num IN ("+placeholders(len(normalized))+")", normalized...).
+		Build()
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer rows.Close()
 
-	return nil
+	var existing []string
+	for rows.Next() {
+		var regNum string
+		if err := rows.Scan(&regNum); err != nil {
+			return nil, storeError(err)
+		}
+		existing = append(existing, regNum)
+	}
+	return existing, storeError(rows.Err())
 }
 
-func ScanIntoCar(rows *sql.Rows) (*Car, error) {
-	car := new(Car)
-	err := rows.Scan(
-		&car.Owner,
-		&car.Mark,
-		&car.Year,
-		&car.RegNum,
-	)
-	return car, err
+// upsertOwner returns the id of the person with the same full name,
+// inserting a new row if there is none.
+func upsertOwner(tx *sql.Tx, owner *People) (int, error) {
+	var id int
+	err := tx.QueryRow(
+		`SELECT id FROM people WHERE name = $1 AND surname = $2 AND COALESCE(patronymic, '') = $3 ORDER BY id LIMIT 1`,
+		owner.Name, owner.Surname, owner.Patronymic,
+	).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	err = tx.QueryRow(
+		`INSERT INTO people (name, surname, patronymic) VALUES ($1, $2, NULLIF($3, '')) RETURNING id`,
+		owner.Name, owner.Surname, owner.Patronymic,
+	).Scan(&id)
+	return id, err
 }
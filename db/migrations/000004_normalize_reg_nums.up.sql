@@ -0,0 +1,2 @@
+UPDATE cars
+SET reg_num = translate(upper(regexp_replace(reg_num, '[[:space:]-]', '', 'g')), 'АВЕКМНОРСТУХ', 'ABEKMHOPCTYX');
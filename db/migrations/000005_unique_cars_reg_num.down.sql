@@ -0,0 +1 @@
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_reg_num_key;
@@ -0,0 +1,19 @@
+-- Refuse to enforce uniqueness over duplicate plates rather than deleting
+-- cars: the duplicates have to be merged or removed by hand first.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(reg_num || ' (cars ' || ids || ')', ', ' ORDER BY reg_num) INTO duplicates
+    FROM (
+        SELECT reg_num, string_agg(id::TEXT, ', ' ORDER BY id) AS ids
+        FROM cars GROUP BY reg_num HAVING COUNT(*) > 1
+    ) d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'duplicate registration numbers: %', duplicates;
+    END IF;
+    -- Schemas created before migrations may already have the constraint.
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = 'cars'::regclass AND conname = 'cars_reg_num_key') THEN
+        ALTER TABLE cars ADD CONSTRAINT cars_reg_num_key UNIQUE (reg_num);
+    END IF;
+END $$;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS people_search_trgm_idx;
+DROP INDEX IF EXISTS people_search_fts_idx;
+DROP INDEX IF EXISTS cars_search_trgm_idx;
+DROP INDEX IF EXISTS cars_search_fts_idx;
@@ -0,0 +1,10 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS cars_search_fts_idx ON cars
+    USING GIN (to_tsvector('simple', mark || ' ' || model || ' ' || reg_num));
+CREATE INDEX IF NOT EXISTS cars_search_trgm_idx ON cars
+    USING GIN ((mark || ' ' || model || ' ' || reg_num) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS people_search_fts_idx ON people
+    USING GIN (to_tsvector('simple', name || ' ' || surname || ' ' || COALESCE(patronymic, '')));
+CREATE INDEX IF NOT EXISTS people_search_trgm_idx ON people
+    USING GIN ((name || ' ' || surname || ' ' || COALESCE(patronymic, '')) gin_trgm_ops);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS people (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL CHECK (length(name) <= 255),
+    surname VARCHAR(255) NOT NULL CHECK (length(surname) <= 255),
+    patronymic VARCHAR(255) CHECK (length(patronymic) <= 255)
+);
+
+CREATE TABLE IF NOT EXISTS cars (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    regNum VARCHAR(20) NOT NULL CHECK (length(regNum) <= 20),
+    mark VARCHAR(255) NOT NULL CHECK (length(mark) <= 255),
+    model VARCHAR(255) NOT NULL CHECK (length(model) <= 255),
+    year INTEGER,
+    owner_id INTEGER REFERENCES people(id)
+);
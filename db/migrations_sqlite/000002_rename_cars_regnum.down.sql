@@ -0,0 +1 @@
+ALTER TABLE cars RENAME COLUMN reg_num TO regnum;
@@ -0,0 +1 @@
+ALTER TABLE cars RENAME COLUMN regnum TO reg_num;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS ownership_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    car_id INTEGER NOT NULL REFERENCES cars(id) ON DELETE CASCADE,
+    person_id INTEGER NOT NULL REFERENCES people(id) ON DELETE RESTRICT,
+    started_at DATE NOT NULL,
+    ended_at DATE
+);
+
+INSERT INTO ownership_history (car_id, person_id, started_at)
+SELECT id, owner_id, date('now') FROM cars c
+WHERE owner_id IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM ownership_history h WHERE h.car_id = c.id AND h.ended_at IS NULL);
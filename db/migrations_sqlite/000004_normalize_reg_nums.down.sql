@@ -0,0 +1 @@
+-- Normalization is lossy; the original spelling of plates cannot be restored.
@@ -0,0 +1,28 @@
+-- SQLite has neither regexp_replace nor translate, and its upper() only
+-- folds ASCII, so separators and Cyrillic homoglyphs of both cases are
+-- replaced one at a time.
+UPDATE cars SET reg_num = upper(replace(replace(replace(reg_num, ' ', ''), char(9), ''), '-', ''));
+UPDATE cars SET reg_num = replace(reg_num, 'А', 'A');
+UPDATE cars SET reg_num = replace(reg_num, 'В', 'B');
+UPDATE cars SET reg_num = replace(reg_num, 'Е', 'E');
+UPDATE cars SET reg_num = replace(reg_num, 'К', 'K');
+UPDATE cars SET reg_num = replace(reg_num, 'М', 'M');
+UPDATE cars SET reg_num = replace(reg_num, 'Н', 'H');
+UPDATE cars SET reg_num = replace(reg_num, 'О', 'O');
+UPDATE cars SET reg_num = replace(reg_num, 'Р', 'P');
+UPDATE cars SET reg_num = replace(reg_num, 'С', 'C');
+UPDATE cars SET reg_num = replace(reg_num, 'Т', 'T');
+UPDATE cars SET reg_num = replace(reg_num, 'У', 'Y');
+UPDATE cars SET reg_num = replace(reg_num, 'Х', 'X');
+UPDATE cars SET reg_num = replace(reg_num, 'а', 'A');
+UPDATE cars SET reg_num = replace(reg_num, 'в', 'B');
+UPDATE cars SET reg_num = replace(reg_num, 'е', 'E');
+UPDATE cars SET reg_num = replace(reg_num, 'к', 'K');
+UPDATE cars SET reg_num = replace(reg_num, 'м', 'M');
+UPDATE cars SET reg_num = replace(reg_num, 'н', 'H');
+UPDATE cars SET reg_num = replace(reg_num, 'о', 'O');
+UPDATE cars SET reg_num = replace(reg_num, 'р', 'P');
+UPDATE cars SET reg_num = replace(reg_num, 'с', 'C');
+UPDATE cars SET reg_num = replace(reg_num, 'т', 'T');
+UPDATE cars SET reg_num = replace(reg_num, 'у', 'Y');
+UPDATE cars SET reg_num = replace(reg_num, 'х', 'X');
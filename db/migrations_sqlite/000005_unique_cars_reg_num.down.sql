@@ -0,0 +1 @@
+DROP INDEX IF EXISTS cars_reg_num_key;
@@ -0,0 +1,4 @@
+-- Duplicate plates make the index creation fail instead of being deleted;
+-- they have to be merged or removed by hand first. List them with
+--     SELECT reg_num, group_concat(id) FROM cars GROUP BY reg_num HAVING COUNT(*) > 1;
+CREATE UNIQUE INDEX IF NOT EXISTS cars_reg_num_key ON cars (reg_num);
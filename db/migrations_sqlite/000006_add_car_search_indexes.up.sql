@@ -0,0 +1,2 @@
+-- SQLite has no trigram or full-text index matching the Postgres search;
+-- SQLiteStore ranks search results in the application instead.
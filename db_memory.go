@@ -0,0 +1,575 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MemoryStore is a Database kept in process memory. It is meant for tests
+// and local development without Postgres and follows the filtering,
+// pagination and error semantics of PostgresStore. Writes work on a copy
+// of the data that replaces it only on success, so a failed call leaves
+// the store unchanged like a rolled back transaction.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	state *memoryState
+}
+
+type memoryState struct {
+	cars    map[int]memoryCar
+	people  map[int]People
+	history map[int]memoryOwnership
+	lastID  map[string]int
+}
+
+// memoryCar is a row of the cars table; the owner is referenced by ID.
+type memoryCar struct {
+	ID      int
+	RegNum  string
+	Mark    string
+	Model   string
+	Year    int
+	OwnerID int
+}
+
+// memoryOwnership is a row of the ownership_history table.
+type memoryOwnership struct {
+	ID       int
+	CarID    int
+	PersonID int
+	Since    time.Time
+	Until    *time.Time
+}
+
+// Column limits of the Postgres schema.
+const (
+	maxRegNumLen = 20
+	maxTextLen   = 255
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{state: &memoryState{
+		cars:    map[int]memoryCar{},
+		people:  map[int]People{},
+		history: map[int]memoryOwnership{},
+		lastID:  map[string]int{},
+	}}
+}
+
+func (st *memoryState) clone() *memoryState {
+	return &memoryState{
+		cars:    maps.Clone(st.cars),
+		people:  maps.Clone(st.people),
+		history: maps.Clone(st.history),
+		lastID:  maps.Clone(st.lastID),
+	}
+}
+
+// nextID returns the next value of the table's serial ID.
+func (st *memoryState) nextID(table string) int {
+	st.lastID[table]++
+	return st.lastID[table]
+}
+
+// read runs f under the read lock.
+func (s *MemoryStore) read(f func(st *memoryState) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return f(s.state)
+}
+
+// write runs f on a copy of the data and keeps the copy if f succeeds.
+func (s *MemoryStore) write(f func(st *memoryState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.state.clone()
+	if err := f(st); err != nil {
+		return err
+	}
+	s.state = st
+	return nil
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// car joins the stored car with its owner.
+func (st *memoryState) car(c memoryCar) *Car {
+	car := &Car{ID: c.ID, RegNum: c.RegNum, Mark: c.Mark, Model: c.Model, Year: c.Year}
+	if owner, ok := st.people[c.OwnerID]; ok {
+		car.Owner = owner
+	}
+	return car
+}
+
+// sortedCars returns all cars joined with their owners, ordered by ID.
+func (st *memoryState) sortedCars() []*Car {
+	cars := make([]*Car, 0, len(st.cars))
+	for _, c := range st.cars {
+		cars = append(cars, st.car(c))
+	}
+	sort.Slice(cars, func(i, j int) bool { return cars[i].ID < cars[j].ID })
+	return cars
+}
+
+func (st *memoryState) carByRegNum(regNum string) (memoryCar, bool) {
+	for _, c := range st.cars {
+		if c.RegNum == regNum {
+			return c, true
+		}
+	}
+	return memoryCar{}, false
+}
+
+// filteredCars returns the cars matching filter, ordered by ID.
+func (st *memoryState) filteredCars(filter CarFilter) []*Car {
+	var cars []*Car
+	for _, car := range st.sortedCars() {
+		if filter.match(car) {
+			cars = append(cars, car)
+		}
+	}
+	return cars
+}
+
+func (s *MemoryStore) GetCars(q CarQuery) (cars []*Car, total int, err error) {
+	err = s.read(func(st *memoryState) error {
+		matched := st.filteredCars(q.Filter)
+		slices.SortStableFunc(matched, func(a, b *Car) int { return compareCars(a, b, q.Sort) })
+		if q.Keyset {
+			if q.After != nil {
+				matched = slices.DeleteFunc(matched, func(car *Car) bool {
+					return compareSortValues(car, q.Sort, func(i int) any { return q.After[i] }) <= 0
+				})
+			}
+			cars = matched[:min(q.PageSize+1, len(matched))]
+			return nil
+		}
+		total = len(matched)
+		from, to := pageBounds(q.Page, q.PageSize, total)
+		cars = matched[from:to]
+		return nil
+	})
+	if len(cars) == 0 {
+		cars = nil
+	}
+	return cars, total, err
+}
+
+func (s *MemoryStore) GetCarByID(id int) (car *Car, err error) {
+	err = s.read(func(st *memoryState) error {
+		c, ok := st.cars[id]
+		if !ok {
+			return NotFoundError("car %d not found", id)
+		}
+		car = st.car(c)
+		return nil
+	})
+	return car, err
+}
+
+func (s *MemoryStore) GetCarByRegNum(regNum string) (car *Car, err error) {
+	regNum = NormalizeRegNum(regNum)
+	err = s.read(func(st *memoryState) error {
+		c, ok := st.carByRegNum(regNum)
+		if !ok {
+			return NotFoundError("car %s not found", regNum)
+		}
+		car = st.car(c)
+		return nil
+	})
+	return car, err
+}
+
+func (s *MemoryStore) DeleteCarByID(id int) error {
+	return s.write(func(st *memoryState) error {
+		if _, ok := st.cars[id]; !ok {
+			return NotFoundError("car %d not found", id)
+		}
+		delete(st.cars, id)
+		maps.DeleteFunc(st.history, func(_ int, h memoryOwnership) bool { return h.CarID == id })
+		return nil
+	})
+}
+
+func (s *MemoryStore) UpdateCarByID(id int, car *Car) error {
+	return s.write(func(st *memoryState) error {
+		c, ok := st.cars[id]
+		if !ok {
+			return NotFoundError("car %d not found", id)
+		}
+		return st.updateCar(id, car, c.OwnerID)
+	})
+}
+
+func (s *MemoryStore) ModifyCarByID(id int, modify func(car *Car) error) error {
+	return s.write(func(st *memoryState) error {
+		c, ok := st.cars[id]
+		if !ok {
+			return NotFoundError("car %d not found", id)
+		}
+		car := st.car(c)
+		if err := modify(car); err != nil {
+			return err
+		}
+		if owner, ok := st.people[c.OwnerID]; ok && car.Owner.ID == owner.ID && car.Owner != owner {
+			if err := checkPerson(&car.Owner); err != nil {
+				return err
+			}
+			st.people[owner.ID] = car.Owner
+		}
+		return st.updateCar(id, car, c.OwnerID)
+	})
+}
+
+// updateCar mirrors the Postgres updateCar helper.
+func (st *memoryState) updateCar(id int, car *Car, currentOwnerID int) error {
+	car.RegNum = NormalizeRegNum(car.RegNum)
+	if car.Owner.ID == 0 && car.Owner.Name != "" {
+		ownerID, err := st.upsertOwner(&car.Owner)
+		if err != nil {
+			return err
+		}
+		car.Owner.ID = ownerID
+	}
+	if err := st.checkCar(car); err != nil {
+		return err
+	}
+	if other, ok := st.carByRegNum(car.RegNum); ok && other.ID != id {
+		return newError(KindConflict, nil, "already exists")
+	}
+
+	st.cars[id] = memoryCar{ID: id, RegNum: car.RegNum, Mark: car.Mark, Model: car.Model, Year: car.Year, OwnerID: car.Owner.ID}
+	if car.Owner.ID != currentOwnerID {
+		st.recordOwnerChange(id, car.Owner.ID, time.Now())
+	}
+	return nil
+}
+
+// checkCar enforces the column limits and the owner foreign key.
+func (st *memoryState) checkCar(car *Car) error {
+	if utf8.RuneCountInString(car.RegNum) > maxRegNumLen ||
+		utf8.RuneCountInString(car.Mark) > maxTextLen || utf8.RuneCountInString(car.Model) > maxTextLen {
+		return newError(KindValidation, nil, "invalid value")
+	}
+	if _, ok := st.people[car.Owner.ID]; car.Owner.ID != 0 && !ok {
+		return newError(KindValidation, nil, "referenced resource does not exist")
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddCars(cars []*Car, onConflict ConflictMode) (statuses []AddCarStatus, err error) {
+	err = s.write(func(st *memoryState) error {
+		statuses = make([]AddCarStatus, len(cars))
+		for i, car := range cars {
+			car.RegNum = NormalizeRegNum(car.RegNum)
+			if c, ok := st.carByRegNum(car.RegNum); ok {
+				switch onConflict {
+				case ConflictSkip:
+					statuses[i] = AddCarStatusSkipped
+				case ConflictUpsert:
+					if err := st.updateCar(c.ID, car, c.OwnerID); err != nil {
+						return err
+					}
+					car.ID = c.ID
+					statuses[i] = AddCarStatusUpdated
+				default:
+					return ConflictError("car %s already exists", car.RegNum)
+				}
+				continue
+			}
+
+			if car.Owner.ID == 0 && car.Owner.Name != "" {
+				id, err := st.upsertOwner(&car.Owner)
+				if err != nil {
+					return err
+				}
+				car.Owner.ID = id
+			}
+			if err := st.checkCar(car); err != nil {
+				return err
+			}
+			id := st.nextID("cars")
+			st.cars[id] = memoryCar{ID: id, RegNum: car.RegNum, Mark: car.Mark, Model: car.Model, Year: car.Year, OwnerID: car.Owner.ID}
+			st.recordOwnerChange(id, car.Owner.ID, time.Now())
+			car.ID = id
+			statuses[i] = AddCarStatusCreated
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func (s *MemoryStore) ExistingRegNums(regNums []string) (existing []string, err error) {
+	err = s.read(func(st *memoryState) error {
+		for _, regNum := range regNums {
+			regNum = NormalizeRegNum(regNum)
+			if _, ok := st.carByRegNum(regNum); ok && !slices.Contains(existing, regNum) {
+				existing = append(existing, regNum)
+			}
+		}
+		return nil
+	})
+	return existing, err
+}
+
+// upsertOwner returns the ID of the person with the same full name,
+// adding the person if there is none.
+func (st *memoryState) upsertOwner(owner *People) (int, error) {
+	match := 0
+	for id, person := range st.people {
+		if person.Name == owner.Name && person.Surname == owner.Surname && person.Patronymic == owner.Patronymic &&
+			(match == 0 || id < match) {
+			match = id
+		}
+	}
+	if match != 0 {
+		return match, nil
+	}
+	if err := checkPerson(owner); err != nil {
+		return 0, err
+	}
+	id := st.nextID("people")
+	st.people[id] = People{ID: id, Name: owner.Name, Surname: owner.Surname, Patronymic: owner.Patronymic}
+	return id, nil
+}
+
+func checkPerson(person *People) error {
+	if utf8.RuneCountInString(person.Name) > maxTextLen || utf8.RuneCountInString(person.Surname) > maxTextLen ||
+		utf8.RuneCountInString(person.Patronymic) > maxTextLen {
+		return newError(KindValidation, nil, "invalid value")
+	}
+	return nil
+}
+
+// pageBounds returns the bounds of the 1-based page within n items. Pages
+// out of range, however large, are empty.
+func pageBounds(page, pageSize, n int) (from, to int) {
+	if page < 1 || pageSize < 1 || page-1 > n/pageSize {
+		return n, n
+	}
+	from = (page - 1) * pageSize
+	return from, from + min(pageSize, n-from)
+}
+
+func (s *MemoryStore) GetPeople(page int, pageSize int, name, surname, patronymic string) (people []*People, err error) {
+	people = []*People{}
+	err = s.read(func(st *memoryState) error {
+		for _, id := range sortedKeys(st.people) {
+			person := st.people[id]
+			if (name != "" && person.Name != name) || (surname != "" && person.Surname != surname) ||
+				(patronymic != "" && person.Patronymic != patronymic) {
+				continue
+			}
+			people = append(people, &person)
+		}
+		return nil
+	})
+	from, to := pageBounds(page, pageSize, len(people))
+	return people[from:to], err
+}
+
+func (s *MemoryStore) GetPersonByID(id int) (person *People, err error) {
+	err = s.read(func(st *memoryState) error {
+		p, ok := st.people[id]
+		if !ok {
+			return NotFoundError("person %d not found", id)
+		}
+		person = &p
+		return nil
+	})
+	return person, err
+}
+
+func (s *MemoryStore) AddPerson(person *People) error {
+	return s.write(func(st *memoryState) error {
+		if err := checkPerson(person); err != nil {
+			return err
+		}
+		person.ID = st.nextID("people")
+		st.people[person.ID] = *person
+		return nil
+	})
+}
+
+func (s *MemoryStore) UpdatePersonByID(id int, person *People) error {
+	return s.ModifyPersonByID(id, func(p *People) error {
+		*p = *person
+		return nil
+	})
+}
+
+func (s *MemoryStore) ModifyPersonByID(id int, modify func(person *People) error) error {
+	return s.write(func(st *memoryState) error {
+		person, ok := st.people[id]
+		if !ok {
+			return NotFoundError("person %d not found", id)
+		}
+		if err := modify(&person); err != nil {
+			return err
+		}
+		person.ID = id
+		if err := checkPerson(&person); err != nil {
+			return err
+		}
+		st.people[id] = person
+		return nil
+	})
+}
+
+// DeletePersonByID removes a person. A person who has ever owned a car
+// cannot be deleted, since that would erase the car's ownership history.
+func (s *MemoryStore) DeletePersonByID(id int) error {
+	return s.write(func(st *memoryState) error {
+		if _, ok := st.people[id]; !ok {
+			return NotFoundError("person %d not found", id)
+		}
+		for _, h := range st.history {
+			if h.PersonID == id {
+				return ConflictError("person %d has ownership history", id)
+			}
+		}
+		delete(st.people, id)
+		return nil
+	})
+}
+
+func (s *MemoryStore) TransferCar(carID int, transfer *Transfer) (ownership *Ownership, err error) {
+	err = s.write(func(st *memoryState) error {
+		c, ok := st.cars[carID]
+		if !ok {
+			return NotFoundError("car %d not found", carID)
+		}
+		if transfer.FromOwnerID != 0 && transfer.FromOwnerID != c.OwnerID {
+			return ConflictError("car %d is not owned by person %d", carID, transfer.FromOwnerID)
+		}
+		if c.OwnerID != 0 && transfer.ToOwnerID == c.OwnerID {
+			return ConflictError("car %d is already owned by person %d", carID, transfer.ToOwnerID)
+		}
+		if transfer.EffectiveDate.After(time.Now()) {
+			return ValidationError(nil, "effective date %s is in the future", transfer.EffectiveDate.Format(time.DateOnly))
+		}
+		if open, ok := st.openOwnership(carID); ok && transfer.EffectiveDate.Before(open.Since) {
+			return ValidationError(nil, "effective date %s is before the current ownership started on %s",
+				transfer.EffectiveDate.Format(time.DateOnly), open.Since.Format(time.DateOnly))
+		}
+		owner, ok := st.people[transfer.ToOwnerID]
+		if !ok {
+			return newError(KindValidation, nil, "referenced resource does not exist")
+		}
+
+		c.OwnerID = transfer.ToOwnerID
+		st.cars[carID] = c
+		st.recordOwnerChange(carID, transfer.ToOwnerID, transfer.EffectiveDate)
+		open, _ := st.openOwnership(carID)
+		ownership = &Ownership{ID: open.ID, CarID: carID, Owner: &owner, Since: transfer.EffectiveDate}
+		return nil
+	})
+	return ownership, err
+}
+
+func (st *memoryState) openOwnership(carID int) (memoryOwnership, bool) {
+	for _, h := range st.history {
+		if h.CarID == carID && h.Until == nil {
+			return h, true
+		}
+	}
+	return memoryOwnership{}, false
+}
+
+// recordOwnerChange closes the open ownership period of the car and, if
+// ownerID is set, opens a new one starting at since. Dates are truncated
+// to days like the DATE columns of the Postgres schema.
+func (st *memoryState) recordOwnerChange(carID, ownerID int, since time.Time) {
+	since = time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.UTC)
+	if open, ok := st.openOwnership(carID); ok {
+		open.Until = &since
+		st.history[open.ID] = open
+	}
+	if ownerID == 0 {
+		return
+	}
+	id := st.nextID("ownership_history")
+	st.history[id] = memoryOwnership{ID: id, CarID: carID, PersonID: ownerID, Since: since}
+}
+
+// ownershipHistory returns the periods accepted by keep, oldest first.
+func (st *memoryState) ownershipHistory(keep func(h memoryOwnership) bool) []memoryOwnership {
+	var history []memoryOwnership
+	for _, h := range st.history {
+		if keep(h) {
+			history = append(history, h)
+		}
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].Since.Equal(history[j].Since) {
+			return history[i].Since.Before(history[j].Since)
+		}
+		return history[i].ID < history[j].ID
+	})
+	return history
+}
+
+func (s *MemoryStore) GetCarOwnershipHistory(carID int) (history []*Ownership, err error) {
+	history = []*Ownership{}
+	err = s.read(func(st *memoryState) error {
+		if _, ok := st.cars[carID]; !ok {
+			return NotFoundError("car %d not found", carID)
+		}
+		for _, h := range st.ownershipHistory(func(h memoryOwnership) bool { return h.CarID == carID }) {
+			owner := st.people[h.PersonID]
+			history = append(history, &Ownership{ID: h.ID, CarID: h.CarID, Owner: &owner, Since: h.Since, Until: h.Until})
+		}
+		return nil
+	})
+	return history, err
+}
+
+func (s *MemoryStore) GetPersonOwnershipHistory(personID int) (history []*Ownership, err error) {
+	history = []*Ownership{}
+	err = s.read(func(st *memoryState) error {
+		if _, ok := st.people[personID]; !ok {
+			return NotFoundError("person %d not found", personID)
+		}
+		for _, h := range st.ownershipHistory(func(h memoryOwnership) bool { return h.PersonID == personID }) {
+			c := st.cars[h.CarID]
+			car := &Car{ID: c.ID, RegNum: c.RegNum, Mark: c.Mark, Model: c.Model, Year: c.Year}
+			history = append(history, &Ownership{ID: h.ID, CarID: h.CarID, Car: car, Since: h.Since, Until: h.Until})
+		}
+		return nil
+	})
+	return history, err
+}
+
+func (s *MemoryStore) SearchCars(text string, limit int) (results []*SearchResult, err error) {
+	err = s.read(func(st *memoryState) error {
+		results = rankCars(st.sortedCars(), text, limit)
+		return nil
+	})
+	return results, err
+}
+
+func (s *MemoryStore) GetCarStats(filter CarFilter, top int) (stats *CarStats, err error) {
+	err = s.read(func(st *memoryState) error {
+		stats = carStats(st.filteredCars(filter), time.Now().Year())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.TopMarks = topStatBuckets(stats.ByMark, top)
+	return stats, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TransferCar hands the car over to transfer.ToOwnerID starting from
+// transfer.EffectiveDate. The previous ownership period is closed on the
+// same date. The date cannot be in the future, since the car's owner
+// changes right away.
+func (s *sqlStore) TransferCar(carID int, transfer *Transfer) (ownership *Ownership, err error) {
+	err = s.inTx(nil, func(tx *sql.Tx) error {
+		var currentOwnerID sql.NullInt64
+		err := tx.QueryRow(`SELECT owner_id FROM cars WHERE id = $1`+s.forUpdate("cars"), carID).Scan(&currentOwnerID)
+		if err == sql.ErrNoRows {
+			return NotFoundError("car %d not found", carID)
+		}
+		if err != nil {
+			return err
+		}
+		if transfer.FromOwnerID != 0 && int64(transfer.FromOwnerID) != currentOwnerID.Int64 {
+			return ConflictError("car %d is not owned by person %d", carID, transfer.FromOwnerID)
+		}
+		if currentOwnerID.Valid && int64(transfer.ToOwnerID) == currentOwnerID.Int64 {
+			return ConflictError("car %d is already owned by person %d", carID, transfer.ToOwnerID)
+		}
+
+		if transfer.EffectiveDate.After(time.Now()) {
+			return ValidationError(nil, "effective date %s is in the future", transfer.EffectiveDate.Format(time.DateOnly))
+		}
+		var since sqlDate
+		err = tx.QueryRow(
+			`SELECT started_at FROM ownership_history WHERE car_id = $1 AND ended_at IS NULL`, carID,
+		).Scan(&since)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil && transfer.EffectiveDate.Before(since.Time) {
+			return ValidationError(nil, "effective date %s is before the current ownership started on %s",
+				transfer.EffectiveDate.Format(time.DateOnly), since.Time.Format(time.DateOnly))
+		}
+
+		if _, err := tx.Exec(`UPDATE cars SET owner_id = $1 WHERE id = $2`, transfer.ToOwnerID, carID); err != nil {
+			return err
+		}
+		ownerID := sql.NullInt64{Int64: int64(transfer.ToOwnerID), Valid: true}
+		if err := recordOwnerChange(tx, carID, ownerID, transfer.EffectiveDate); err != nil {
+			return err
+		}
+
+		ownership = &Ownership{CarID: carID, Owner: new(People), Since: transfer.EffectiveDate}
+		return tx.QueryRow(
+			`SELECT h.id, p.id, p.name, p.surname, COALESCE(p.patronymic, '')
+            FROM ownership_history h JOIN people p ON p.id = h.person_id
+            WHERE h.car_id = $1 AND h.ended_at IS NULL`, carID,
+		).Scan(&ownership.ID, &ownership.Owner.ID, &ownership.Owner.Name, &ownership.Owner.Surname, &ownership.Owner.Patronymic)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ownership, nil
+}
+
+func (s *sqlStore) GetCarOwnershipHistory(carID int) ([]*Ownership, error) {
+	rows, err := s.db.Query(`
+        SELECT h.id, h.car_id, h.started_at, h.ended_at, p.id, p.name, p.surname, COALESCE(p.patronymic, '')
+        FROM ownership_history h JOIN people p ON p.id = h.person_id
+        WHERE h.car_id = $1
+        ORDER BY h.started_at, h.id`, carID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer rows.Close()
+
+	history := []*Ownership{}
+	for rows.Next() {
+		ownership := &Ownership{Owner: new(People)}
+		var since, until sqlDate
+		err := rows.Scan(
+			&ownership.ID,
+			&ownership.CarID,
+			&since,
+			&until,
+			&ownership.Owner.ID,
+			&ownership.Owner.Name,
+			&ownership.Owner.Surname,
+			&ownership.Owner.Patronymic,
+		)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		ownership.Since = since.Time
+		if until.Valid {
+			ownership.Until = &until.Time
+		}
+		history = append(history, ownership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storeError(err)
+	}
+	if len(history) == 0 {
+		if err := s.mustExist("cars", "car", carID); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+func (s *sqlStore) GetPersonOwnershipHistory(personID int) ([]*Ownership, error) {
+	rows, err := s.db.Query(`
+        SELECT h.id, h.car_id, h.started_at, h.ended_at, c.id, c.reg_num, c.mark, c.model, COALESCE(c.year, 0)
+        FROM ownership_history h JOIN cars c ON c.id = h.car_id
+        WHERE h.person_id = $1
+        ORDER BY h.started_at, h.id`, personID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer rows.Close()
+
+	history := []*Ownership{}
+	for rows.Next() {
+		ownership := &Ownership{Car: new(Car)}
+		var since, until sqlDate
+		err := rows.Scan(
+			&ownership.ID,
+			&ownership.CarID,
+			&since,
+			&until,
+			&ownership.Car.ID,
+			&ownership.Car.RegNum,
+			&ownership.Car.Mark,
+			&ownership.Car.Model,
+			&ownership.Car.Year,
+		)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		ownership.Since = since.Time
+		if until.Valid {
+			ownership.Until = &until.Time
+		}
+		history = append(history, ownership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storeError(err)
+	}
+	if len(history) == 0 {
+		if err := s.mustExist("people", "person", personID); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+// recordOwnerChange closes the open ownership period of the car and, if
+// ownerID is set, opens a new one starting at since.
+func recordOwnerChange(tx *sql.Tx, carID int, ownerID sql.NullInt64, since time.Time) error {
+	_, err := tx.Exec(
+		`UPDATE ownership_history SET ended_at = $1 WHERE car_id = $2 AND ended_at IS NULL`,
+		dateValue(since), carID,
+	)
+	if err != nil || !ownerID.Valid {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO ownership_history (car_id, person_id, started_at) VALUES ($1, $2, $3)`,
+		carID, ownerID.Int64, dateValue(since),
+	)
+	return err
+}
+
+// mustExist returns a NotFoundError if table has no row with id. what
+// names the row in the error.
+func (s *sqlStore) mustExist(table, what string, id int) error {
+	var found bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, id).Scan(&found)
+	if err != nil {
+		return storeError(err)
+	}
+	if !found {
+		return NotFoundError("%s %d not found", what, id)
+	}
+	return nil
+}
+
+// sqlDate scans a DATE column, which Postgres returns as a time and SQLite
+// as the stored text.
+type sqlDate struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (d *sqlDate) Scan(value any) (err error) {
+	switch v := value.(type) {
+	case nil:
+		*d = sqlDate{}
+		return nil
+	case time.Time:
+		d.Time = v
+	case string:
+		d.Time, err = time.Parse(time.DateOnly, v[:min(len(v), len(time.DateOnly))])
+	case []byte:
+		d.Time, err = time.Parse(time.DateOnly, string(v[:min(len(v), len(time.DateOnly))]))
+	default:
+		return fmt.Errorf("cannot scan %T into a date", value)
+	}
+	d.Valid = err == nil
+	return err
+}
+
+// dateValue formats t for a DATE column, which both databases accept as
+// text, so the date is the one of t's location.
+func dateValue(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
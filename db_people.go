@@ -0,0 +1,141 @@
+package main
+
+import "database/sql"
+
+func (s *sqlStore) GetPeople(page int, pageSize int, name, surname, patronymic string) ([]*People, error) {
+	people := []*People{}
+
+	query, args := newQuery("SELECT id, name, surname, COALESCE(patronymic, '') FROM people").
+		WhereIf(name != "", "name = ?", name).
+		WhereIf(surname != "", "surname = ?", surname).
+		WhereIf(patronymic != "", "patronymic = ?", patronymic).
+		OrderBy("id").
+		Paginate(page, pageSize).
+		Build()
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		person, err := ScanIntoPerson(rows)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		people = append(people, person)
+	}
+	return people, storeError(rows.Err())
+}
+
+func (s *sqlStore) GetPersonByID(id int) (*People, error) {
+	rows, err := s.db.Query("SELECT id, name, surname, COALESCE(patronymic, '') FROM people WHERE id = $1", id)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, storeError(err)
+		}
+		return nil, NotFoundError("person %d not found", id)
+	}
+	person, err := ScanIntoPerson(rows)
+	return person, storeError(err)
+}
+
+func (s *sqlStore) AddPerson(person *People) error {
+	err := s.db.QueryRow(
+		`INSERT INTO people (name, surname, patronymic) VALUES ($1, $2, NULLIF($3, '')) RETURNING id`,
+		person.Name, person.Surname, person.Patronymic,
+	).Scan(&person.ID)
+	return storeError(err)
+}
+
+func (s *sqlStore) UpdatePersonByID(id int, person *People) error {
+	res, err := s.db.Exec(
+		`UPDATE people SET name = $1, surname = $2, patronymic = NULLIF($3, '') WHERE id = $4`,
+		person.Name, person.Surname, person.Patronymic, id,
+	)
+	if err != nil {
+		return storeError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return storeError(err)
+	}
+	if n == 0 {
+		return NotFoundError("person %d not found", id)
+	}
+	person.ID = id
+	return nil
+}
+
+// ModifyPersonByID loads the person, lets modify change it and stores the
+// result within one transaction.
+func (s *sqlStore) ModifyPersonByID(id int, modify func(person *People) error) error {
+	return s.inTx(nil, func(tx *sql.Tx) error {
+		person := new(People)
+		err := tx.QueryRow(
+			"SELECT id, name, surname, COALESCE(patronymic, '') FROM people WHERE id = $1"+s.forUpdate("people"), id,
+		).Scan(&person.ID, &person.Name, &person.Surname, &person.Patronymic)
+		if err == sql.ErrNoRows {
+			return NotFoundError("person %d not found", id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := modify(person); err != nil {
+			return err
+		}
+		person.ID = id
+		return updatePerson(tx, id, person)
+	})
+}
+
+// updatePerson overwrites the personal data of the person with id.
+func updatePerson(tx *sql.Tx, id int, person *People) error {
+	_, err := tx.Exec(
+		`UPDATE people SET name = $1, surname = $2, patronymic = NULLIF($3, '') WHERE id = $4`,
+		person.Name, person.Surname, person.Patronymic, id,
+	)
+	return err
+}
+
+// DeletePersonByID removes a person. A person who has ever owned a car
+// cannot be deleted, since that would erase the car's ownership history.
+func (s *sqlStore) DeletePersonByID(id int) error {
+	return s.inTx(nil, func(tx *sql.Tx) error {
+		var owned bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM ownership_history WHERE person_id = $1)`, id).Scan(&owned)
+		if err != nil {
+			return err
+		}
+		if owned {
+			return ConflictError("person %d has ownership history", id)
+		}
+		res, err := tx.Exec(`DELETE FROM people WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return NotFoundError("person %d not found", id)
+		}
+		return nil
+	})
+}
+
+func ScanIntoPerson(rows *sql.Rows) (*People, error) {
+	person := new(People)
+	err := rows.Scan(
+		&person.ID,
+		&person.Name,
+		&person.Surname,
+		&person.Patronymic,
+	)
+	return person, err
+}
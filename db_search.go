@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// searchSimilarityThreshold is the minimum pg_trgm word similarity for a
+// fuzzy match. It is low enough to tolerate a typo per word.
+const searchSimilarityThreshold = 0.3
+
+// The search documents. They must stay identical to the expressions of the
+// indexes created by the search migration so the planner can use them.
+const (
+	carSearchText   = `(c.mark || ' ' || c.model || ' ' || c.reg_num)`
+	ownerSearchText = `(p.name || ' ' || p.surname || ' ' || COALESCE(p.patronymic, ''))`
+)
+
+// SearchCars matches text against mark, model, registration number and the
+// owner's full name using full-text search and trigram similarity, best
+// matches first.
+func (s *PostgresStore) SearchCars(text string, limit int) (results []*SearchResult, err error) {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, searchSimilarityThreshold); err != nil {
+		return nil, storeError(err)
+	}
+
+	// The search text is bound once and shared by every expression.
+	q := newQuery("")
+	search := q.Bind(text)
+	tsQuery := `websearch_to_tsquery('simple', ` + search + `)`
+	const carVector = `to_tsvector('simple', ` + carSearchText + `)`
+	const ownerVector = `to_tsvector('simple', ` + ownerSearchText + `)`
+	q.Select(`SELECT ` + carColumns + `,
+            ts_rank(` + carVector + ` || COALESCE(` + ownerVector + `, ''), ` + tsQuery + `)
+            + GREATEST(word_similarity(` + search + `, ` + carSearchText + `), COALESCE(word_similarity(` + search + `, ` + ownerSearchText + `), 0)) AS score` + carFrom)
+	match := carVector + ` @@ ` + tsQuery + ` OR ` + ownerVector + ` @@ ` + tsQuery +
+		` OR ` + search + ` <% ` + carSearchText + ` OR ` + search + ` <% ` + ownerSearchText
+	if regNum := NormalizeRegNum(text); regNum != "" {
+		q.Where(`(`+match+` OR c.reg_num LIKE ?)`, likeContains(regNum))
+	} else {
+		q.Where(`(` + match + `)`)
+	}
+	query, args := q.OrderBy("score DESC", "c.id").Paginate(1, limit).Build()
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer rows.Close()
+
+	results = []*SearchResult{}
+	for rows.Next() {
+		result := &SearchResult{Car: new(Car)}
+		if err := rows.Scan(append(carDest(result.Car), &result.Score)...); err != nil {
+			return nil, storeError(err)
+		}
+		results = append(results, result)
+	}
+	return results, storeError(rows.Err())
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// SQLiteStore is a Database on a SQLite file for deployments without
+// Postgres. It shares its statements with PostgresStore; search and
+// statistics are computed in the application since SQLite lacks the
+// Postgres search and date functions.
+type SQLiteStore struct {
+	sqlStore
+}
+
+func init() {
+	// SQLite's built-in lower() only folds ASCII. The case-insensitive
+	// filters compare lower() of both sides, so replace it with a Unicode
+	// aware version to match Postgres on Cyrillic data.
+	sqlite.MustRegisterDeterministicScalarFunction("lower", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return strings.ToLower(v), nil
+		case []byte:
+			return strings.ToLower(string(v)), nil
+		}
+		return args[0], nil
+	})
+}
+
+// NewSQLiteStore opens the database file named by SQLITE_PATH, cartest.db
+// by default.
+func NewSQLiteStore() (*SQLiteStore, error) {
+	path, ok := os.LookupEnv("SQLITE_PATH")
+	if !ok || path == "" {
+		path = "cartest.db"
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time, and foreign keys are enabled per
+	// connection, so all calls share a single connection.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`PRAGMA foreign_keys = ON`); err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{sqlStore{db: db}}, nil
+}
+
+func (s *SQLiteStore) GetCarStats(filter CarFilter, top int) (stats *CarStats, err error) {
+	err = s.inTx(snapshot, func(tx *sql.Tx) error {
+		query, args := filter.apply(newQuery(`SELECT ` + carColumns + carFrom)).Build()
+		cars, err := queryCars(tx, query, args)
+		if err != nil {
+			return err
+		}
+		stats = carStats(cars, time.Now().Year())
+		stats.TopMarks = topStatBuckets(stats.ByMark, top)
+		return nil
+	})
+	return stats, err
+}
+
+func (s *SQLiteStore) SearchCars(text string, limit int) (results []*SearchResult, err error) {
+	err = s.inTx(snapshot, func(tx *sql.Tx) error {
+		cars, err := queryCars(tx, selectCarQuery+" ORDER BY c.id", nil)
+		results = rankCars(cars, text, limit)
+		return err
+	})
+	return results, err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Age ranges are in years since the model year, oldest range last.
+const carAgeBucket = `CASE
+            WHEN year IS NULL THEN '` + unknownStatKey + `'
+            WHEN age <= 2 THEN '0-2'
+            WHEN age <= 5 THEN '3-5'
+            WHEN age <= 10 THEN '6-10'
+            WHEN age <= 20 THEN '11-20'
+            ELSE '21+'
+        END`
+
+// GetCarStats aggregates the cars matching filter. All aggregates run over
+// the same read-only snapshot so they add up to the same total.
+func (s *PostgresStore) GetCarStats(filter CarFilter, top int) (stats *CarStats, err error) {
+	tx, err := s.db.BeginTx(context.Background(), snapshot)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer tx.Rollback()
+
+	filtered, args := filter.apply(newQuery(`SELECT c.mark, c.model, NULLIF(c.year, 0) AS year,
+            EXTRACT(YEAR FROM CURRENT_DATE)::int - NULLIF(c.year, 0) AS age, c.owner_id` + carFrom)).Build()
+	with := "WITH f AS (" + filtered + ") "
+
+	stats = new(CarStats)
+	err = tx.QueryRow(with+`SELECT COUNT(*) FROM f`, args...).Scan(&stats.Total)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	err = tx.QueryRow(with+`SELECT COUNT(*) FROM (
+            SELECT owner_id FROM f WHERE owner_id IS NOT NULL GROUP BY owner_id HAVING COUNT(*) > 1
+        ) o`, args...).Scan(&stats.MultiCarOwners)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if stats.ByMark, err = statBuckets(tx, with+`SELECT mark, COUNT(*) FROM f
+            GROUP BY mark ORDER BY COUNT(*) DESC, mark`, args); err != nil {
+		return nil, storeError(err)
+	}
+	if stats.ByYear, err = statBuckets(tx, with+`SELECT COALESCE(year::text, '`+unknownStatKey+`'), COUNT(*) FROM f
+            GROUP BY year ORDER BY year NULLS LAST`, args); err != nil {
+		return nil, storeError(err)
+	}
+	if stats.ByDecade, err = statBuckets(tx, with+`SELECT COALESCE((year / 10 * 10)::text || 's', '`+unknownStatKey+`'), COUNT(*) FROM f
+            GROUP BY 1 ORDER BY MIN(year) NULLS LAST`, args); err != nil {
+		return nil, storeError(err)
+	}
+	if stats.AgeDistribution, err = statBuckets(tx, with+`SELECT `+carAgeBucket+` AS bucket, COUNT(*) FROM f
+            GROUP BY bucket ORDER BY MIN(age) NULLS LAST`, args); err != nil {
+		return nil, storeError(err)
+	}
+	stats.TopMarks = topStatBuckets(stats.ByMark, top)
+
+	rows, err := tx.Query(with+`SELECT mark, model, COUNT(*) FROM f
+            GROUP BY mark, model ORDER BY COUNT(*) DESC, mark, model`, args...)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	defer rows.Close()
+	stats.ByModel = []ModelStat{}
+	for rows.Next() {
+		var model ModelStat
+		if err := rows.Scan(&model.Mark, &model.Model, &model.Count); err != nil {
+			return nil, storeError(err)
+		}
+		stats.ByModel = append(stats.ByModel, model)
+	}
+	return stats, storeError(rows.Err())
+}
+
+// statBuckets runs a query selecting a key and a count.
+func statBuckets(tx *sql.Tx, query string, args []any) ([]StatBucket, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	buckets := []StatBucket{}
+	for rows.Next() {
+		var bucket StatBucket
+		if err := rows.Scan(&bucket.Key, &bucket.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
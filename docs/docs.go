@@ -17,7 +17,7 @@ const docTemplate = `{
     "paths": {
         "/cars/add": {
             "post": {
-                "description": "Add one or more cars",
+                "description": "Add one or more cars. Car info is looked up concurrently for every registration number;\nresponds with 201 when all lookups succeed and 207 with per-plate results otherwise.\nonConflict decides what happens to plates that are already stored: reject fails the request\nwith 409, skip leaves them untouched and upsert re-enriches and updates them.",
                 "consumes": [
                     "application/json"
                 ],
@@ -30,23 +30,88 @@ const docTemplate = `{
                 "summary": "AddCarHandler",
                 "parameters": [
                     {
-                        "description": "Registration numbers of cars (comma-separated)",
-                        "name": "regNums",
+                        "description": "Registration numbers of cars",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.AddCarsRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successful response with an array of added cars",
+                        "description": "All cars were added",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Car"
-                            }
+                            "$ref": "#/definitions/main.AddCarsResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Some cars could not be added",
+                        "schema": {
+                            "$ref": "#/definitions/main.AddCarsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Some cars already exist",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "502": {
+                        "description": "Car info API failure",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/by-reg/{regNum}": {
+            "get": {
+                "description": "Get a car by registration number",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cars"
+                ],
+                "summary": "GetCarByRegNumHandler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Registration number",
+                        "name": "regNum",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The car",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
                         }
                     },
                     "400": {
@@ -55,6 +120,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIError"
                         }
                     },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -105,6 +182,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIError"
                         }
                     },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -116,7 +199,7 @@ const docTemplate = `{
         },
         "/cars/get": {
             "get": {
-                "description": "Get a list of cars with pagination support",
+                "description": "Get a list of cars with pagination support. Text filters are case-insensitive;\n*_prefix and *_contains parameters match partially.\nPassing cursor or limit switches to keyset pagination: page and page_size are ignored and\nthe response carries nextCursor to fetch the following page with the same sort order.",
                 "consumes": [
                     "application/json"
                 ],
@@ -130,24 +213,48 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Page number",
+                        "description": "Page number (at most 1000000)",
                         "name": "page",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "Number of items per page",
+                        "description": "Number of items per page (at most 100)",
                         "name": "page_size",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Car make",
+                        "description": "Opaque cursor from nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page in keyset mode (at most 100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car mark (alias of mark)",
                         "name": "make",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Car mark",
+                        "name": "mark",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of car marks",
+                        "name": "marks",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Car model",
@@ -159,16 +266,85 @@ const docTemplate = `{
                         "description": "Car year",
                         "name": "year",
                         "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum car year",
+                        "name": "year_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum car year",
+                        "name": "year_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car mark prefix",
+                        "name": "mark_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car mark substring",
+                        "name": "mark_contains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car model prefix",
+                        "name": "model_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car model substring",
+                        "name": "model_contains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Registration number prefix",
+                        "name": "regNum_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Registration number substring",
+                        "name": "regNum_contains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner name prefix",
+                        "name": "owner_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner surname prefix",
+                        "name": "owner_surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner patronymic prefix",
+                        "name": "owner_patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated sort keys: id, year, mark, regNum, owner.surname; prefix with - for descending",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful response with an array of cars",
+                        "description": "Page of cars with pagination metadata, also described by the Link and X-Total-Count headers. In keyset mode the body has items, limit, nextCursor and next instead",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Car"
-                            }
+                            "$ref": "#/definitions/main.PageResponse-main_Car"
                         }
                     },
                     "400": {
@@ -183,6 +359,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIError"
                         }
                     },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -192,9 +374,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/cars/update/{id}": {
-            "put": {
-                "description": "Update a car by ID",
+        "/cars/search": {
+            "get": {
+                "description": "Search cars by free text over mark, model, registration number and owner name. Matching tolerates typos; results are ordered by relevance",
                 "consumes": [
                     "application/json"
                 ],
@@ -204,21 +386,30 @@ const docTemplate = `{
                 "tags": [
                     "cars"
                 ],
-                "summary": "UpdateCarHandler",
+                "summary": "SearchCarsHandler",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Car ID",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Search text",
+                        "name": "q",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "ID of the updated car",
+                        "description": "Matching cars, best first",
                         "schema": {
-                            "type": "integer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.SearchResult"
+                            }
                         }
                     },
                     "400": {
@@ -227,8 +418,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.APIError"
                         }
                     },
-                    "404": {
-                        "description": "Resource not found",
+                    "422": {
+                        "description": "Validation failed",
                         "schema": {
                             "$ref": "#/definitions/main.APIError"
                         }
@@ -241,48 +432,1356 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.APIError": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                }
-            }
-        },
-        "main.Car": {
-            "type": "object",
-            "properties": {
-                "mark": {
-                    "type": "string"
-                },
-                "model": {
-                    "type": "string"
-                },
-                "owner": {
-                    "$ref": "#/definitions/main.People"
-                },
-                "regNum": {
-                    "type": "string"
-                },
-                "year": {
-                    "type": "integer"
-                }
-            }
         },
-        "main.People": {
-            "type": "object",
-            "properties": {
-                "name": {
-                    "type": "string"
-                },
-                "patronymic": {
-                    "type": "string"
-                },
-                "surname": {
-                    "type": "string"
+        "/cars/stats": {
+            "get": {
+                "description": "Get fleet statistics: counts by mark, model, year and decade, the age distribution,\nthe number of owners with more than one car and the top marks. Accepts the same\nfilters as /cars/get, applied before aggregating.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cars"
+                ],
+                "summary": "GetCarStatsHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of top marks (default 5, at most 100)",
+                        "name": "top",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car mark (alias of mark)",
+                        "name": "make",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car mark",
+                        "name": "mark",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of car marks",
+                        "name": "marks",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car model",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Car year",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum car year",
+                        "name": "year_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum car year",
+                        "name": "year_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car mark prefix",
+                        "name": "mark_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car mark substring",
+                        "name": "mark_contains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car model prefix",
+                        "name": "model_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Car model substring",
+                        "name": "model_contains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Registration number prefix",
+                        "name": "regNum_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Registration number substring",
+                        "name": "regNum_contains",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner name prefix",
+                        "name": "owner_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner surname prefix",
+                        "name": "owner_surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner patronymic prefix",
+                        "name": "owner_patronymic",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statistics of the matching cars",
+                        "schema": {
+                            "$ref": "#/definitions/main.CarStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}": {
+            "get": {
+                "description": "Get a car by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cars"
+                ],
+                "summary": "GetCarHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Car ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The car",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a car by ID. Fields missing from the body are cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cars"
+                ],
+                "summary": "ReplaceCarHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Car ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New car state",
+                        "name": "car",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The updated car",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Partially update a car by ID. Accepts a JSON Merge Patch (RFC 7396), where only the supplied\nfields of the car and its owner change, or a JSON Patch (RFC 6902) operation list applied atomically.\nChanged fields of the current owner are stored on that person and so apply to every car they own;\nsetting owner.id to another person, or to 0 with a full name, replaces the owner instead.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cars"
+                ],
+                "summary": "UpdateCarHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Car ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch or JSON Patch operations",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The updated car",
+                        "schema": {
+                            "$ref": "#/definitions/main.Car"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "A JSON Patch test operation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported patch format",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/owners": {
+            "get": {
+                "description": "Get the ownership timeline of a car, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cars"
+                ],
+                "summary": "GetCarOwnersHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Car ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ownership periods of the car",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Ownership"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/transfer": {
+            "post": {
+                "description": "Transfer a car to another owner. The effective date defaults to today and cannot be in the future.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cars"
+                ],
+                "summary": "TransferCarHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Car ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transfer details",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "The new ownership period",
+                        "schema": {
+                            "$ref": "#/definitions/main.Ownership"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Car is not owned by the given person",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Report whether the service can serve requests. The database schema is compared\nwith the columns the store uses; any drift is listed and makes the check fail.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "HealthHandler",
+                "responses": {
+                    "200": {
+                        "description": "Service is healthy",
+                        "schema": {
+                            "$ref": "#/definitions/main.HealthResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "503": {
+                        "description": "Database schema drifted from what the store expects",
+                        "schema": {
+                            "$ref": "#/definitions/main.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/add": {
+            "post": {
+                "description": "Add a car owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "AddPersonHandler",
+                "parameters": [
+                    {
+                        "description": "Owner to add",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.People"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "The created owner",
+                        "schema": {
+                            "$ref": "#/definitions/main.People"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/delete/{id}": {
+            "delete": {
+                "description": "Delete a car owner by ID. Owners with ownership history cannot be deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "DeletePersonHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ID of the deleted owner",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "Person has ownership history",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/get": {
+            "get": {
+                "description": "Get a list of car owners with pagination support",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "GetPeopleHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number (at most 1000000)",
+                        "name": "page",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page (at most 100)",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner surname",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Owner patronymic",
+                        "name": "patronymic",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response with an array of owners",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.People"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}": {
+            "get": {
+                "description": "Get a car owner by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "GetPersonHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The owner",
+                        "schema": {
+                            "$ref": "#/definitions/main.People"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a car owner by ID. Fields missing from the body are cleared. All cars referencing the owner see the change.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "ReplacePersonHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New owner state",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.People"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The updated owner",
+                        "schema": {
+                            "$ref": "#/definitions/main.People"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Partially update a car owner by ID with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "PatchPersonHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch or JSON Patch operations",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.People"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The updated owner",
+                        "schema": {
+                            "$ref": "#/definitions/main.People"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "409": {
+                        "description": "A JSON Patch test operation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported patch format",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}/cars": {
+            "get": {
+                "description": "Get every car a person has ever owned, oldest ownership first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "GetPersonCarsHandler",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ownership periods of the person",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Ownership"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Resource not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.APIError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.AddCarResult": {
+            "type": "object",
+            "properties": {
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "code": {
+                    "$ref": "#/definitions/main.ErrorKind"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "regNum": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/main.AddCarStatus"
+                }
+            }
+        },
+        "main.AddCarStatus": {
+            "type": "string",
+            "enum": [
+                "created",
+                "updated",
+                "skipped",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "AddCarStatusCreated",
+                "AddCarStatusUpdated",
+                "AddCarStatusSkipped",
+                "AddCarStatusFailed"
+            ]
+        },
+        "main.AddCarsRequest": {
+            "type": "object",
+            "properties": {
+                "onConflict": {
+                    "default": "reject",
+                    "enum": [
+                        "reject",
+                        "skip",
+                        "upsert"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.ConflictMode"
+                        }
+                    ]
+                },
+                "regNums": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.AddCarsResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AddCarResult"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.Car": {
+            "type": "object",
+            "required": [
+                "mark",
+                "model",
+                "regNum"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "mark": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "model": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "owner": {
+                    "$ref": "#/definitions/main.People"
+                },
+                "regNum": {
+                    "type": "string",
+                    "maxLength": 20
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.CarStats": {
+            "type": "object",
+            "properties": {
+                "ageDistribution": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.StatBucket"
+                    }
+                },
+                "byDecade": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.StatBucket"
+                    }
+                },
+                "byMark": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.StatBucket"
+                    }
+                },
+                "byModel": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.ModelStat"
+                    }
+                },
+                "byYear": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.StatBucket"
+                    }
+                },
+                "multiCarOwners": {
+                    "type": "integer"
+                },
+                "topMarks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.StatBucket"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.ConflictMode": {
+            "type": "string",
+            "enum": [
+                "reject",
+                "skip",
+                "upsert"
+            ],
+            "x-enum-varnames": [
+                "ConflictReject",
+                "ConflictSkip",
+                "ConflictUpsert"
+            ]
+        },
+        "main.ErrorKind": {
+            "type": "string",
+            "enum": [
+                "bad_request",
+                "not_found",
+                "conflict",
+                "validation",
+                "upstream",
+                "internal",
+                "unsupported_media_type"
+            ],
+            "x-enum-varnames": [
+                "KindBadRequest",
+                "KindNotFound",
+                "KindConflict",
+                "KindValidation",
+                "KindUpstream",
+                "KindInternal",
+                "KindUnsupportedMediaType"
+            ]
+        },
+        "main.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "schemaDrift": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.SchemaDrift"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "main.ModelStat": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "mark": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Ownership": {
+            "type": "object",
+            "properties": {
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "carId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "owner": {
+                    "$ref": "#/definitions/main.People"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PageResponse-main_Car": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Car"
+                    }
+                },
+                "next": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "prev": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "totalPages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.People": {
+            "type": "object",
+            "required": [
+                "name",
+                "surname"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "patronymic": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "surname": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "main.SchemaDrift": {
+            "type": "object",
+            "properties": {
+                "column": {
+                    "type": "string"
+                },
+                "got": {
+                    "type": "string"
+                },
+                "table": {
+                    "type": "string"
+                },
+                "want": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.SearchResult": {
+            "type": "object",
+            "properties": {
+                "car": {
+                    "$ref": "#/definitions/main.Car"
+                },
+                "score": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.StatBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.TransferRequest": {
+            "type": "object",
+            "properties": {
+                "effectiveDate": {
+                    "type": "string",
+                    "example": "2024-03-01"
+                },
+                "fromOwnerId": {
+                    "type": "integer"
+                },
+                "toOwnerId": {
+                    "type": "integer",
+                    "minimum": 1
                 }
             }
         }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+const defaultEnrichWorkers = 8
+
+type AddCarResult struct {
+	RegNum string       `json:"regNum"`
+	Status AddCarStatus `json:"status"`
+	Car    *Car         `json:"car,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Code   ErrorKind    `json:"code,omitempty"`
+}
+
+type AddCarsResponse struct {
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Skipped int             `json:"skipped"`
+	Failed  int             `json:"failed"`
+	Results []*AddCarResult `json:"results"`
+}
+
+// enrichCars looks up car info for every regNum using at most workers
+// concurrent provider calls. Results keep the order of regNums.
+func enrichCars(ctx context.Context, provider CarInfoProvider, regNums []string, workers int) []*AddCarResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]*AddCarResult, len(regNums))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(regNums); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				regNum := regNums[idx]
+				car, err := provider.GetCarInfo(ctx, regNum)
+				if err != nil {
+					results[idx] = &AddCarResult{
+						RegNum: regNum,
+						Status: AddCarStatusFailed,
+						Error:  err.Error(),
+						Code:   ErrorKindOf(err),
+					}
+					continue
+				}
+				results[idx] = &AddCarResult{RegNum: regNum, Status: AddCarStatusCreated, Car: car}
+			}
+		}()
+	}
+	for idx := range regNums {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
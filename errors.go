@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrorKind classifies an Error and decides the HTTP status it maps to.
+type ErrorKind string
+
+const (
+	KindBadRequest ErrorKind = "bad_request"
+	KindNotFound   ErrorKind = "not_found"
+	KindConflict   ErrorKind = "conflict"
+	KindValidation ErrorKind = "validation"
+	KindUpstream   ErrorKind = "upstream"
+	KindInternal   ErrorKind = "internal"
+
+	KindUnsupportedMediaType ErrorKind = "unsupported_media_type"
+)
+
+// Error is the domain error returned by the Database and CarInfoProvider
+// layers and by request parsing in handlers.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+	// Fields lists the individual failures of a validation error.
+	Fields []FieldError
+	// driver marks errors wrapping a database driver error, whose text
+	// describes the schema and is only logged.
+	driver bool
+}
+
+// FieldError describes why a single request field was rejected. Code is
+// stable and meant for clients; Message is for humans.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error of the same kind, so that
+// errors.Is(err, ErrNotFound) matches every not found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind
+}
+
+// Sentinels for errors.Is checks.
+var (
+	ErrBadRequest = &Error{Kind: KindBadRequest, Message: "bad request"}
+	ErrNotFound   = &Error{Kind: KindNotFound, Message: "not found"}
+	ErrConflict   = &Error{Kind: KindConflict, Message: "conflict"}
+	ErrValidation = &Error{Kind: KindValidation, Message: "validation failed"}
+	ErrUpstream   = &Error{Kind: KindUpstream, Message: "upstream failure"}
+	ErrInternal   = &Error{Kind: KindInternal, Message: "internal error"}
+)
+
+func newError(kind ErrorKind, err error, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+func BadRequestError(err error, format string, args ...any) error {
+	return newError(KindBadRequest, err, format, args...)
+}
+
+func NotFoundError(format string, args ...any) error {
+	return newError(KindNotFound, nil, format, args...)
+}
+
+func ConflictError(format string, args ...any) error {
+	return newError(KindConflict, nil, format, args...)
+}
+
+// driverError wraps an error of the database driver.
+func driverError(kind ErrorKind, err error, message string) error {
+	e := newError(kind, err, "%s", message)
+	e.driver = true
+	return e
+}
+
+func ValidationError(err error, format string, args ...any) error {
+	return newError(KindValidation, err, format, args...)
+}
+
+// FieldValidationError reports a validation failure of a single field.
+func FieldValidationError(err error, field, code, format string, args ...any) error {
+	e := newError(KindValidation, err, format, args...)
+	e.Fields = []FieldError{{Field: field, Code: code, Message: e.Message}}
+	return e
+}
+
+func UpstreamError(err error, format string, args ...any) error {
+	return newError(KindUpstream, err, format, args...)
+}
+
+// ErrorKindOf returns the kind of err. Errors that are not an *Error are
+// internal.
+func ErrorKindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// ErrorStatus returns the HTTP status for err. Errors that are not an
+// *Error are treated as internal.
+func ErrorStatus(err error) int {
+	switch ErrorKindOf(err) {
+	case KindBadRequest:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindValidation:
+		return http.StatusUnprocessableEntity
+	case KindUpstream:
+		return http.StatusBadGateway
+	case KindUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusInternalServerError
+}
+
+// storeError translates driver errors into domain errors. Constraint
+// violations become conflict or validation errors, anything else that is
+// not already an *Error is reported as internal.
+func storeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return driverError(KindNotFound, err, "not found")
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return driverError(KindConflict, err, "already exists")
+		case "foreign_key_violation":
+			return driverError(KindValidation, err, "referenced resource does not exist")
+		case "not_null_violation", "string_data_right_truncation", "check_violation":
+			return driverError(KindValidation, err, "invalid value")
+		}
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return driverError(KindConflict, err, "already exists")
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return driverError(KindValidation, err, "referenced resource does not exist")
+		case sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+			return driverError(KindValidation, err, "invalid value")
+		}
+	}
+	return driverError(KindInternal, err, "database error")
+}
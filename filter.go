@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// CarQuery selects a page of the car list. With Keyset set, pages are
+// addressed by After, the sort key values of the last car seen, instead
+// of Page, and one extra car is fetched to detect a following page.
+type CarQuery struct {
+	Page     int
+	PageSize int
+	Filter   CarFilter
+	Sort     []SortKey
+	Keyset   bool
+	After    []any
+}
+
+// CarFilter narrows down the car list. Zero values disable a criterion.
+// Text matches are case-insensitive.
+type CarFilter struct {
+	// Marks matches any of the listed marks exactly.
+	Marks []string
+	Model string
+	Year  int
+	// YearFrom and YearTo bound the year inclusively.
+	YearFrom int
+	YearTo   int
+
+	MarkPrefix     string
+	MarkContains   string
+	ModelPrefix    string
+	ModelContains  string
+	RegNumPrefix   string
+	RegNumContains string
+
+	// Owner fields match by prefix.
+	OwnerName       string
+	OwnerSurname    string
+	OwnerPatronymic string
+}
+
+// ParseCarFilter reads the filter query parameters of the car list,
+// recording violations in v. make and mark are exact matches; marks takes
+// a comma-separated list.
+func ParseCarFilter(r *http.Request, v *Validator) CarFilter {
+	query := r.URL.Query()
+	f := CarFilter{
+		Model:           query.Get("model"),
+		Year:            v.QueryInt(r, "year", false, 0),
+		YearFrom:        v.QueryInt(r, "year_from", false, 0),
+		YearTo:          v.QueryInt(r, "year_to", false, 0),
+		MarkPrefix:      query.Get("mark_prefix"),
+		MarkContains:    query.Get("mark_contains"),
+		ModelPrefix:     query.Get("model_prefix"),
+		ModelContains:   query.Get("model_contains"),
+		RegNumPrefix:    NormalizeRegNum(query.Get("regNum_prefix")),
+		RegNumContains:  NormalizeRegNum(query.Get("regNum_contains")),
+		OwnerName:       query.Get("owner_name"),
+		OwnerSurname:    query.Get("owner_surname"),
+		OwnerPatronymic: query.Get("owner_patronymic"),
+	}
+	for _, name := range []string{"make", "mark"} {
+		if mark := query.Get(name); mark != "" {
+			f.Marks = append(f.Marks, mark)
+		}
+	}
+	for _, mark := range strings.Split(query.Get("marks"), ",") {
+		if mark = strings.TrimSpace(mark); mark != "" {
+			f.Marks = append(f.Marks, mark)
+		}
+	}
+
+	v.Check("year", f.Year, "year")
+	v.Check("year_from", f.YearFrom, "year")
+	v.Check("year_to", f.YearTo, "year")
+	if f.YearFrom != 0 && f.YearTo != 0 && f.YearFrom > f.YearTo {
+		v.Add("year_to", "out_of_range", "must not be less than year_from")
+	}
+	v.Check("marks", f.Marks, "max=50")
+	return f
+}
+
+// apply adds the filter conditions to q. The query must alias cars as c
+// and the owner join on people as p. The conditions are valid in both
+// Postgres and SQLite, which lacks ILIKE and a default LIKE escape.
+func (f CarFilter) apply(q *queryBuilder) *queryBuilder {
+	marks := make([]any, len(f.Marks))
+	for i, mark := range f.Marks {
+		marks[i] = strings.ToLower(mark)
+	}
+	return q.
+		WhereIf(len(marks) > 0, "lower(c.mark) IN ("+placeholders(len(marks))+")", marks...).
+		WhereIf(f.Model != "", "lower(c.model) = lower(?)", f.Model).
+		WhereIf(f.Year != 0, "c.year = ?", f.Year).
+		WhereIf(f.YearFrom != 0, "c.year >= ?", f.YearFrom).
+		WhereIf(f.YearTo != 0, "c.year <= ?", f.YearTo).
+		WhereIf(f.MarkPrefix != "", `lower(c.mark) LIKE lower(?) ESCAPE '\'`, likePrefix(f.MarkPrefix)).
+		WhereIf(f.MarkContains != "", `lower(c.mark) LIKE lower(?) ESCAPE '\'`, likeContains(f.MarkContains)).
+		WhereIf(f.ModelPrefix != "", `lower(c.model) LIKE lower(?) ESCAPE '\'`, likePrefix(f.ModelPrefix)).
+		WhereIf(f.ModelContains != "", `lower(c.model) LIKE lower(?) ESCAPE '\'`, likeContains(f.ModelContains)).
+		WhereIf(f.RegNumPrefix != "", `c.reg_num LIKE ? ESCAPE '\'`, likePrefix(f.RegNumPrefix)).
+		WhereIf(f.RegNumContains != "", `c.reg_num LIKE ? ESCAPE '\'`, likeContains(f.RegNumContains)).
+		WhereIf(f.OwnerName != "", `lower(p.name) LIKE lower(?) ESCAPE '\'`, likePrefix(f.OwnerName)).
+		WhereIf(f.OwnerSurname != "", `lower(p.surname) LIKE lower(?) ESCAPE '\'`, likePrefix(f.OwnerSurname)).
+		WhereIf(f.OwnerPatronymic != "", `lower(p.patronymic) LIKE lower(?) ESCAPE '\'`, likePrefix(f.OwnerPatronymic))
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func likePrefix(s string) string {
+	return likeEscaper.Replace(s) + "%"
+}
+
+func likeContains(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// match reports whether car passes the filter, mirroring apply for stores
+// that filter in memory. A zero year and a missing owner behave like SQL
+// NULLs and fail every criterion on them.
+func (f CarFilter) match(car *Car) bool {
+	hasPrefix := func(s, prefix string) bool {
+		return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+	}
+	contains := func(s, substr string) bool {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+	}
+	if len(f.Marks) > 0 && !slices.ContainsFunc(f.Marks, func(mark string) bool { return strings.EqualFold(mark, car.Mark) }) {
+		return false
+	}
+	if car.Year == 0 && (f.Year != 0 || f.YearFrom != 0 || f.YearTo != 0) {
+		return false
+	}
+	owned := car.Owner.ID != 0
+	if !owned && (f.OwnerName != "" || f.OwnerSurname != "" || f.OwnerPatronymic != "") {
+		return false
+	}
+	return (f.Model == "" || strings.EqualFold(car.Model, f.Model)) &&
+		(f.Year == 0 || car.Year == f.Year) &&
+		(f.YearFrom == 0 || car.Year >= f.YearFrom) &&
+		(f.YearTo == 0 || car.Year <= f.YearTo) &&
+		(f.MarkPrefix == "" || hasPrefix(car.Mark, f.MarkPrefix)) &&
+		(f.MarkContains == "" || contains(car.Mark, f.MarkContains)) &&
+		(f.ModelPrefix == "" || hasPrefix(car.Model, f.ModelPrefix)) &&
+		(f.ModelContains == "" || contains(car.Model, f.ModelContains)) &&
+		(f.RegNumPrefix == "" || strings.HasPrefix(car.RegNum, f.RegNumPrefix)) &&
+		(f.RegNumContains == "" || strings.Contains(car.RegNum, f.RegNumContains)) &&
+		(f.OwnerName == "" || hasPrefix(car.Owner.Name, f.OwnerName)) &&
+		(f.OwnerSurname == "" || hasPrefix(car.Owner.Surname, f.OwnerSurname)) &&
+		(f.OwnerPatronymic == "" || hasPrefix(car.Owner.Patronymic, f.OwnerPatronymic))
+}
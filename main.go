@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 	_ "github.com/smnov/cartest/docs"
@@ -10,19 +12,49 @@ import (
 
 func main() {
 	l := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	slog.SetDefault(l)
 	if err := godotenv.Load(); err != nil {
 		l.Info("No .env file found")
 	}
-	db, err := NewPostgresStore()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrateCommand(context.Background(), os.Args[2:]); err != nil {
+			l.Error("migrate failed", "error", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	db, err := NewStore(context.Background())
+	if err != nil {
+		panic(err.Error())
+	}
+	if err := CheckSchemaOnStartup(context.Background(), db, l); err != nil {
+		panic(err.Error())
+	}
+	providerCfg, err := NewHTTPProviderConfig()
 	if err != nil {
 		panic(err.Error())
 	}
+	provider := NewHTTPCarInfoProvider(providerCfg)
+	country, exists := os.LookupEnv("REGNUM_COUNTRY")
+	if !exists {
+		country = "RU"
+	}
+	if err := SetPlateCountry(country); err != nil {
+		panic(err.Error())
+	}
 	parsePort, exists := os.LookupEnv("SERVER_PORT")
 	port := string(":" + parsePort)
 	if !exists {
 		l.Info("port variable not found, using default instead")
 		port = ":8080"
 	}
-	s := NewServer(port, db, l)
+	s := NewServer(port, db, provider, l)
+	if workersStr, ok := os.LookupEnv("ENRICH_WORKERS"); ok {
+		workers, err := strconv.Atoi(workersStr)
+		if err != nil {
+			panic(err.Error())
+		}
+		s.enrichWorkers = workers
+	}
 	s.Start()
 }
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed db/migrations/*.sql
+var postgresMigrations embed.FS
+
+//go:embed db/migrations_sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// migrationLockID keys the Postgres advisory lock held while migrating.
+const migrationLockID = 7358213901
+
+// The version table is the one of the migrate CLI, so databases migrated
+// with it keep their version.
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    dirty BOOLEAN NOT NULL
+)`
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies the migrations embedded in the binary and records the
+// schema version in schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []migration
+	// lock and unlock hold a lock across instances for the duration of a
+	// run. They are empty for SQLite, which serializes writers itself.
+	lock, unlock string
+	// columnQuery counts the columns named by its table and column
+	// arguments. It is used to recognize a schema created by initTables.
+	columnQuery string
+}
+
+// MigrationStatus is the state of one migration.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+func (s *PostgresStore) Migrator() (*Migrator, error) {
+	migrations, err := loadMigrations(postgresMigrations, "db/migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         s.db,
+		migrations: migrations,
+		lock:       fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationLockID),
+		unlock:     fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockID),
+		columnQuery: `SELECT COUNT(*) FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = '%s' AND column_name = '%s'`,
+	}, nil
+}
+
+func (s *SQLiteStore) Migrator() (*Migrator, error) {
+	migrations, err := loadMigrations(sqliteMigrations, "db/migrations_sqlite")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:          s.db,
+		migrations:  migrations,
+		columnQuery: `SELECT COUNT(*) FROM pragma_table_info('%s') WHERE name = '%s'`,
+	}, nil
+}
+
+// migrateCommand runs "migrate up", "migrate down [steps]" or "migrate
+// status" against the database selected by DB_DRIVER. down reverts one
+// migration unless steps is given.
+func migrateCommand(ctx context.Context, args []string) error {
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	mstore, ok := store.(migratable)
+	if !ok {
+		return fmt.Errorf("DB_DRIVER %q has no schema to migrate", os.Getenv("DB_DRIVER"))
+	}
+	m, err := mstore.Migrator()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		fmt.Printf("applied %d migrations\n", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		fmt.Printf("reverted %d migrations\n", reverted)
+		return err
+	case "status":
+		version, statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("schema version %d\n", version)
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q", args[0])
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// loadMigrations reads the migrations in dir, ordered by version. Every
+// version needs both an up and a down file.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %06d_%s lacks an up or down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (applied int, err error) {
+	err = m.run(ctx, func(conn *sql.Conn, version int) error {
+		for _, mig := range m.migrations {
+			if mig.Version <= version {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig.Up, mig.Version); err != nil {
+				return fmt.Errorf("migration %06d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts up to steps applied migrations, newest first, and returns
+// how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (reverted int, err error) {
+	err = m.run(ctx, func(conn *sql.Conn, version int) error {
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			mig := m.migrations[i]
+			if mig.Version > version {
+				continue
+			}
+			previous := 0
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := m.apply(ctx, conn, mig.Down, previous); err != nil {
+				return fmt.Errorf("reverting migration %06d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns the current schema version and the state of every
+// migration.
+func (m *Migrator) Status(ctx context.Context) (version int, statuses []MigrationStatus, err error) {
+	err = m.run(ctx, func(conn *sql.Conn, current int) error {
+		version = current
+		for _, mig := range m.migrations {
+			statuses = append(statuses, MigrationStatus{Version: mig.Version, Name: mig.Name, Applied: mig.Version <= current})
+		}
+		return nil
+	})
+	return version, statuses, err
+}
+
+// run calls f on a dedicated connection holding the migration lock, with
+// the current schema version. A dirty version, left behind by a failed
+// run of the migrate CLI, must be repaired by hand first.
+func (m *Migrator) run(ctx context.Context, f func(conn *sql.Conn, version int) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.lock); err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), m.unlock)
+	}
+	if _, err := conn.ExecContext(ctx, createVersionTable); err != nil {
+		return err
+	}
+
+	version, dirty, recorded, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty; repair the schema and clear schema_migrations.dirty", version)
+	}
+	if !recorded {
+		if version, err = m.baseline(ctx, conn); err != nil {
+			return err
+		}
+	}
+	return f(conn, version)
+}
+
+// baseline records the version of a schema that initTables created before
+// migrations were tracked, so that they are not applied on top of it. The
+// column name only tells whether regnum was renamed, which is version 2.
+// Versions of initTables that went further also created some of the later
+// tables, constraints and indexes, so migrations 3 to 6 skip what exists.
+func (m *Migrator) baseline(ctx context.Context, conn *sql.Conn) (int, error) {
+	hasColumn := func(table, column string) (bool, error) {
+		var n int
+		err := conn.QueryRowContext(ctx, fmt.Sprintf(m.columnQuery, table, column)).Scan(&n)
+		return n > 0, err
+	}
+	version := 0
+	if ok, err := hasColumn("cars", "reg_num"); err != nil {
+		return 0, err
+	} else if ok {
+		version = 2
+	} else if ok, err := hasColumn("cars", "regnum"); err != nil {
+		return 0, err
+	} else if ok {
+		version = 1
+	}
+	if version == 0 {
+		return 0, nil
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := setSchemaVersion(ctx, tx, version); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// apply runs a migration script and records version in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, version int) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if hasStatements(script) {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := setSchemaVersion(ctx, tx, version); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func schemaVersion(ctx context.Context, conn *sql.Conn) (version int, dirty, recorded bool, err error) {
+	err = conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, false, nil
+	}
+	return version, dirty, err == nil, err
+}
+
+// setSchemaVersion records version; version 0 means no migration is
+// applied and leaves the table empty.
+func setSchemaVersion(ctx context.Context, tx *sql.Tx, version int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil || version == 0 {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO schema_migrations (version, dirty) VALUES (%d, false)`, version))
+	return err
+}
+
+// hasStatements reports whether script contains more than comments.
+func hasStatements(script string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageResponse wraps one page of a list together with the pagination
+// metadata clients need to render page numbers.
+type PageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"totalPages"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+}
+
+// NewPageResponse builds the envelope for items and sets the RFC 8288 Link
+// header with first, prev, next and last relations on w.
+func NewPageResponse[T any](w http.ResponseWriter, r *http.Request, items []T, page, pageSize, total int) *PageResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	resp := &PageResponse[T]{
+		Items:      items,
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	}
+
+	links := []string{pageLink(r, 1, "first")}
+	if page > 1 {
+		resp.Prev = pageURL(r, min(page-1, max(resp.TotalPages, 1)))
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, resp.Prev))
+	}
+	if page < resp.TotalPages {
+		resp.Next = pageURL(r, page+1)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, resp.Next))
+	}
+	links = append(links, pageLink(r, max(resp.TotalPages, 1), "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	return resp
+}
+
+// pageURL returns the request URL with the page parameter replaced. The
+// URL is relative to the host so it works behind proxies.
+func pageURL(r *http.Request, page int) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+func pageLink(r *http.Request, page int, rel string) string {
+	return fmt.Sprintf(`<%s>; rel="%s"`, pageURL(r, page), rel)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewPageResponse(t *testing.T) {
+	url := func(page int) string {
+		return fmt.Sprintf("/cars/get?mark=lada&page=%d&page_size=2", page)
+	}
+	link := func(page int, rel string) string {
+		return fmt.Sprintf(`<%s>; rel="%s"`, url(page), rel)
+	}
+	tests := []struct {
+		name       string
+		page       int
+		total      int
+		totalPages int
+		prev, next string
+		links      []string
+	}{
+		{"first page", 1, 5, 3, "", url(2), []string{link(1, "first"), link(2, "next"), link(3, "last")}},
+		{"middle page", 2, 5, 3, url(1), url(3), []string{link(1, "first"), link(1, "prev"), link(3, "next"), link(3, "last")}},
+		{"last page", 3, 5, 3, url(2), "", []string{link(1, "first"), link(2, "prev"), link(3, "last")}},
+		{"past the end", 7, 5, 3, url(3), "", []string{link(1, "first"), link(3, "prev"), link(3, "last")}},
+		{"no items", 1, 0, 0, "", "", []string{link(1, "first"), link(1, "last")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", fmt.Sprintf("/cars/get?page=%d&page_size=2&mark=lada", tt.page), nil)
+			w := httptest.NewRecorder()
+			resp := NewPageResponse[*Car](w, r, nil, tt.page, 2, tt.total)
+
+			if resp.Items == nil {
+				t.Error("items are nil, want an empty list")
+			}
+			if resp.Total != tt.total || resp.TotalPages != tt.totalPages {
+				t.Errorf("total = %d in %d pages, want %d in %d", resp.Total, resp.TotalPages, tt.total, tt.totalPages)
+			}
+			if resp.Prev != tt.prev || resp.Next != tt.next {
+				t.Errorf("prev = %q, next = %q, want %q and %q", resp.Prev, resp.Next, tt.prev, tt.next)
+			}
+			if got, want := w.Header().Get("Link"), strings.Join(tt.links, ", "); got != want {
+				t.Errorf("Link = %s\nwant %s", got, want)
+			}
+			if got, want := w.Header().Get("X-Total-Count"), fmt.Sprint(tt.total); got != want {
+				t.Errorf("X-Total-Count = %s, want %s", got, want)
+			}
+		})
+	}
+}
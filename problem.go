@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const problemMediaType = "application/problem+json"
+
+// APIError is an RFC 7807 problem details object. Code repeats the error
+// kind in a form clients can switch on; Errors lists per-field validation
+// failures.
+type APIError struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// NewAPIError builds the problem details for err. Details of internal
+// errors and the text of database driver errors are not exposed to clients.
+func NewAPIError(r *http.Request, err error) *APIError {
+	kind := ErrorKindOf(err)
+	status := ErrorStatus(err)
+	problem := &APIError{
+		Type:     "/problems/" + strings.ReplaceAll(string(kind), "_", "-"),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+		Code:     string(kind),
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		problem.Errors = e.Fields
+	}
+	switch {
+	case kind == KindInternal:
+	case e != nil && e.driver:
+		problem.Detail = e.Message
+	default:
+		problem.Detail = err.Error()
+	}
+	return problem
+}
+
+// withholdsDetail reports whether the response for err leaves out details
+// that only the log shows.
+func withholdsDetail(err error) bool {
+	var e *Error
+	return ErrorKindOf(err) == KindInternal || errors.As(err, &e) && e.driver
+}
+
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) error {
+	problem := NewAPIError(r, err)
+	w.Header().Set("Content-Type", problemMediaType)
+	w.WriteHeader(problem.Status)
+	return json.NewEncoder(w).Encode(problem)
+}
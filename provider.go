@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CarInfoProvider looks up car details by registration number.
+type CarInfoProvider interface {
+	GetCarInfo(ctx context.Context, regNum string) (*Car, error)
+}
+
+type HTTPProviderConfig struct {
+	BaseURL string
+	Timeout time.Duration
+	Headers map[string]string
+}
+
+// NewHTTPProviderConfig reads the external API settings from the environment.
+// CAR_INFO_API_HEADERS is a comma-separated list of "Key: Value" pairs.
+func NewHTTPProviderConfig() (HTTPProviderConfig, error) {
+	cfg := HTTPProviderConfig{
+		BaseURL: "http://external-api.com",
+		Timeout: 10 * time.Second,
+		Headers: map[string]string{},
+	}
+	if baseURL, ok := os.LookupEnv("CAR_INFO_API_URL"); ok && baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	if timeoutStr, ok := os.LookupEnv("CAR_INFO_API_TIMEOUT"); ok && timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid CAR_INFO_API_TIMEOUT: %w", err)
+		}
+		cfg.Timeout = timeout
+	}
+	if headersStr, ok := os.LookupEnv("CAR_INFO_API_HEADERS"); ok && headersStr != "" {
+		for _, pair := range strings.Split(headersStr, ",") {
+			key, value, found := strings.Cut(pair, ":")
+			if !found {
+				return cfg, fmt.Errorf("invalid CAR_INFO_API_HEADERS entry: %q", pair)
+			}
+			cfg.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return cfg, nil
+}
+
+// HTTPCarInfoProvider fetches car details from the external info API.
+type HTTPCarInfoProvider struct {
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewHTTPCarInfoProvider(cfg HTTPProviderConfig) *HTTPCarInfoProvider {
+	return &HTTPCarInfoProvider{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (p *HTTPCarInfoProvider) GetCarInfo(ctx context.Context, regNum string) (*Car, error) {
+	apiUrl := p.baseURL + "/info?regNum=" + url.QueryEscape(regNum)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiUrl, nil)
+	if err != nil {
+		return nil, newError(KindInternal, err, "building car info request failed")
+	}
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+
+	response, err := p.client.Do(req)
+	if err != nil {
+		return nil, UpstreamError(err, "car info request failed")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, NotFoundError("no car info for regNum %s", regNum)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, UpstreamError(nil, "API request failed with status code: %d", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, UpstreamError(err, "reading car info response failed")
+	}
+
+	var car Car
+	if err := json.Unmarshal(body, &car); err != nil {
+		return nil, UpstreamError(err, "invalid car info response")
+	}
+
+	return &car, nil
+}
+
+// StaticCarInfoProvider serves car details from memory. It is meant for
+// tests, demos and local development without the external API. Plates are
+// matched in normalized form.
+type StaticCarInfoProvider struct {
+	mu   sync.RWMutex
+	cars map[string]Car
+}
+
+func NewStaticCarInfoProvider(cars ...Car) *StaticCarInfoProvider {
+	p := &StaticCarInfoProvider{cars: make(map[string]Car, len(cars))}
+	for _, car := range cars {
+		p.cars[NormalizeRegNum(car.RegNum)] = car
+	}
+	return p
+}
+
+func (p *StaticCarInfoProvider) Set(car Car) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cars[NormalizeRegNum(car.RegNum)] = car
+}
+
+func (p *StaticCarInfoProvider) GetCarInfo(ctx context.Context, regNum string) (*Car, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	car, ok := p.cars[NormalizeRegNum(regNum)]
+	if !ok {
+		return nil, NotFoundError("no car info for regNum %s", regNum)
+	}
+	return &car, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryBuilder assembles SELECT statements with positional placeholders.
+// Values only ever travel as arguments; the SQL fragments passed to Where
+// and OrderBy must be constants or come from an allowlist.
+type queryBuilder struct {
+	base       string
+	conditions []string
+	args       []any
+	orderBy    []string
+	limit      int
+	offset     int
+}
+
+func newQuery(base string) *queryBuilder {
+	return &queryBuilder{base: base}
+}
+
+// Where adds a condition joined with AND. Every ? in cond is replaced with
+// a placeholder bound to the next value of args.
+func (q *queryBuilder) Where(cond string, args ...any) *queryBuilder {
+	var b strings.Builder
+	next := 0
+	for _, r := range cond {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		if next == len(args) {
+			panic("query: more placeholders than arguments in " + cond)
+		}
+		b.WriteString(q.bind(args[next]))
+		next++
+	}
+	if next != len(args) {
+		panic("query: more arguments than placeholders in " + cond)
+	}
+	q.conditions = append(q.conditions, b.String())
+	return q
+}
+
+// WhereIf adds the condition only when ok is true.
+func (q *queryBuilder) WhereIf(ok bool, cond string, args ...any) *queryBuilder {
+	if ok {
+		q.Where(cond, args...)
+	}
+	return q
+}
+
+func (q *queryBuilder) OrderBy(exprs ...string) *queryBuilder {
+	q.orderBy = append(q.orderBy, exprs...)
+	return q
+}
+
+// Paginate limits the result to the given 1-based page.
+func (q *queryBuilder) Paginate(page, pageSize int) *queryBuilder {
+	q.limit = pageSize
+	q.offset = (page - 1) * pageSize
+	return q
+}
+
+// Bind binds arg and returns its placeholder, for a value that is used
+// more than once or outside a condition, such as in a computed column.
+func (q *queryBuilder) Bind(arg any) string {
+	return q.bind(arg)
+}
+
+// Select replaces the statement that conditions are appended to. Bind
+// values referenced by it first.
+func (q *queryBuilder) Select(base string) *queryBuilder {
+	q.base = base
+	return q
+}
+
+func (q *queryBuilder) bind(arg any) string {
+	q.args = append(q.args, arg)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// Build returns the statement and its arguments.
+func (q *queryBuilder) Build() (string, []any) {
+	var b strings.Builder
+	b.WriteString(q.base)
+	if len(q.conditions) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(q.conditions, " AND "))
+	}
+	if len(q.orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	args := q.args
+	if q.limit > 0 {
+		args = append(args, q.limit, q.offset)
+		fmt.Fprintf(&b, " LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+	return b.String(), args
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var hostileInputs = []string{
+	`'`,
+	`O'Brien`,
+	`'; DROP TABLE cars;--`,
+	`" OR 1=1 --`,
+	`%`,
+	`_`,
+	`\`,
+	`50%_off\`,
+	`? OR ?`,
+	`$1`,
+}
+
+// textFilter sets every text criterion of a filter to s.
+func textFilter(s string) CarFilter {
+	return CarFilter{
+		Marks:           []string{s},
+		Model:           s,
+		MarkPrefix:      s,
+		MarkContains:    s,
+		ModelPrefix:     s,
+		ModelContains:   s,
+		RegNumPrefix:    s,
+		RegNumContains:  s,
+		OwnerName:       s,
+		OwnerSurname:    s,
+		OwnerPatronymic: s,
+	}
+}
+
+func TestCarFilterApplyBindsHostileInput(t *testing.T) {
+	wantSQL, _ := textFilter("x").apply(newQuery(selectCarQuery)).Build()
+	for _, input := range hostileInputs {
+		t.Run(input, func(t *testing.T) {
+			sql, args := textFilter(input).apply(newQuery(selectCarQuery)).Build()
+			if sql != wantSQL {
+				t.Errorf("SQL depends on the input:\n got %s\nwant %s", sql, wantSQL)
+			}
+			want := []any{
+				strings.ToLower(input),
+				input,
+				likePrefix(input),
+				likeContains(input),
+				likePrefix(input),
+				likeContains(input),
+				likePrefix(input),
+				likeContains(input),
+				likePrefix(input),
+				likePrefix(input),
+				likePrefix(input),
+			}
+			if !reflect.DeepEqual(args, want) {
+				t.Errorf("args = %q, want %q", args, want)
+			}
+		})
+	}
+}
+
+func TestLikePatternsEscapeWildcards(t *testing.T) {
+	tests := []struct {
+		in, prefix, contains string
+	}{
+		{"abc", `abc%`, `%abc%`},
+		{"%", `\%%`, `%\%%`},
+		{"_", `\_%`, `%\_%`},
+		{`\`, `\\%`, `%\\%`},
+		{`50%_off\`, `50\%\_off\\%`, `%50\%\_off\\%`},
+		{`'`, `'%`, `%'%`},
+	}
+	for _, tt := range tests {
+		if got := likePrefix(tt.in); got != tt.prefix {
+			t.Errorf("likePrefix(%q) = %q, want %q", tt.in, got, tt.prefix)
+		}
+		if got := likeContains(tt.in); got != tt.contains {
+			t.Errorf("likeContains(%q) = %q, want %q", tt.in, got, tt.contains)
+		}
+	}
+}
+
+func TestQueryBuilderPlaceholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		q        *queryBuilder
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "postgres",
+			q:        newQuery("SELECT id FROM cars").Where("mark = ?", "a").Where("year BETWEEN ? AND ?", 1990, 2000),
+			wantSQL:  "SELECT id FROM cars WHERE mark = $1 AND year BETWEEN $2 AND $3",
+			wantArgs: []any{"a", 1990, 2000},
+		},
+		{
+			name:     "paginated",
+			q:        newQuery("SELECT id FROM cars").Where("mark = ?", "a").OrderBy("id").Paginate(3, 10),
+			wantSQL:  "SELECT id FROM cars WHERE mark = $1 ORDER BY id LIMIT $2 OFFSET $3",
+			wantArgs: []any{"a", 10, 20},
+		},
+		{
+			name:     "skipped condition",
+			q:        newQuery("SELECT id FROM cars").WhereIf(false, "mark = ?", "a").WhereIf(true, "model = ?", "b"),
+			wantSQL:  "SELECT id FROM cars WHERE model = $1",
+			wantArgs: []any{"b"},
+		},
+		{
+			name: "bound before select",
+			q: func() *queryBuilder {
+				q := newQuery("")
+				text := q.Bind("x")
+				return q.Select("SELECT f("+text+") FROM cars").Where("g("+text+", ?)", "y")
+			}(),
+			wantSQL:  "SELECT f($1) FROM cars WHERE g($1, $2)",
+			wantArgs: []any{"x", "y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.q.Build()
+			if sql != tt.wantSQL {
+				t.Errorf("SQL = %s, want %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderArgumentCountPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		cond string
+		args []any
+		want string
+	}{
+		{"missing argument", "mark = ? AND model = ?", []any{"a"}, "more placeholders than arguments"},
+		{"extra argument", "mark = ?", []any{"a", "b"}, "more arguments than placeholders"},
+		{"no placeholder", "mark = 'a'", []any{"a"}, "more arguments than placeholders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.want) {
+					t.Errorf("panic = %q, want it to contain %q", msg, tt.want)
+				}
+			}()
+			newQuery("SELECT id FROM cars").Where(tt.cond, tt.args...)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// regNumHomoglyphs maps the Cyrillic letters allowed on Russian plates to
+// the Latin letters they look like. Normalized plates use the Latin form.
+var regNumHomoglyphs = map[rune]rune{
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H',
+	'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X',
+}
+
+// NormalizeRegNum returns the canonical form of a registration number:
+// upper case, without whitespace or hyphens and with Cyrillic homoglyphs
+// folded to Latin, so "а123вс 77" and "A123BC77" compare equal.
+func NormalizeRegNum(regNum string) string {
+	var b strings.Builder
+	b.Grow(len(regNum))
+	for _, r := range regNum {
+		if unicode.IsSpace(r) || r == '-' {
+			continue
+		}
+		r = unicode.ToUpper(r)
+		if latin, ok := regNumHomoglyphs[r]; ok {
+			r = latin
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// PlateFormat reports whether a normalized registration number is valid
+// in a country.
+type PlateFormat func(regNum string) bool
+
+var (
+	plateFormats = map[string]PlateFormat{}
+	// plateCountry selects the format the "regnum" validation rule checks.
+	// When empty, any plate made of letters and digits is accepted.
+	plateCountry string
+)
+
+// RegisterPlateFormat adds or replaces the format for an ISO 3166-1
+// alpha-2 country code.
+func RegisterPlateFormat(country string, format PlateFormat) {
+	plateFormats[strings.ToUpper(country)] = format
+}
+
+// SetPlateCountry selects the country whose plate format is enforced.
+func SetPlateCountry(country string) error {
+	country = strings.ToUpper(country)
+	if _, ok := plateFormats[country]; country != "" && !ok {
+		return fmt.Errorf("no plate format registered for country %q", country)
+	}
+	plateCountry = country
+	return nil
+}
+
+var genericPlatePattern = regexp.MustCompile(`^[\p{L}\d]+$`)
+
+// ValidRegNum reports whether regNum, once normalized, matches the plate
+// format of the selected country.
+func ValidRegNum(regNum string) bool {
+	regNum = NormalizeRegNum(regNum)
+	if format, ok := plateFormats[plateCountry]; ok {
+		return format(regNum)
+	}
+	return genericPlatePattern.MatchString(regNum)
+}
+
+// russianPlatePatterns are the GOST R 50577 formats in normalized form,
+// each followed by a two or three digit region code.
+var russianPlatePatterns = []*regexp.Regexp{
+	// Private cars: A123BC77
+	regexp.MustCompile(`^[ABEKMHOPCTYX]\d{3}[ABEKMHOPCTYX]{2}\d{2,3}$`),
+	// Taxis and buses: AB12377
+	regexp.MustCompile(`^[ABEKMHOPCTYX]{2}\d{3}\d{2,3}$`),
+	// Trailers: AB123477
+	regexp.MustCompile(`^[ABEKMHOPCTYX]{2}\d{4}\d{2,3}$`),
+	// Motorcycles: 1234AB77
+	regexp.MustCompile(`^\d{4}[ABEKMHOPCTYX]{2}\d{2,3}$`),
+	// Transit: AB123C77
+	regexp.MustCompile(`^[ABEKMHOPCTYX]{2}\d{3}[ABEKMHOPCTYX]\d{2,3}$`),
+	// Police: A123477
+	regexp.MustCompile(`^[ABEKMHOPCTYX]\d{4}\d{2,3}$`),
+}
+
+func init() {
+	RegisterPlateFormat("RU", func(regNum string) bool {
+		for _, pattern := range russianPlatePatterns {
+			if pattern.MatchString(regNum) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestNormalizeRegNum(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"а123вс77", "A123BC77"},
+		{"A123BC 77", "A123BC77"},
+		{"a 123 bc77", "A123BC77"},
+		{"А123ВС-777", "A123BC777"},
+		{"a\t123\nbc 77", "A123BC77"},
+		{"АВЕКМНОРСТУХ", "ABEKMHOPCTYX"},
+		{"авекмнорстух", "ABEKMHOPCTYX"},
+		{"ж123дб77", "Ж123ДБ77"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRegNum(tt.in); got != tt.want {
+			t.Errorf("NormalizeRegNum(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidRegNumRussia(t *testing.T) {
+	if err := SetPlateCountry("ru"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetPlateCountry("") })
+
+	tests := []struct {
+		format string
+		regNum string
+		valid  bool
+	}{
+		{"private", "A123BC77", true},
+		{"private", "а123вс 777", true},
+		{"private", "A123BD77", false},
+		{"private", "A123BC7", false},
+		{"taxi", "AB12377", true},
+		{"taxi", "AB1237", false},
+		{"trailer", "AB123477", true},
+		{"trailer", "AZ123477", false},
+		{"motorcycle", "1234AB77", true},
+		{"motorcycle", "1234AB7", false},
+		{"transit", "AB123C77", true},
+		{"transit", "AB123CD77", false},
+		{"police", "A123477", true},
+		{"police", "A12345", false},
+	}
+	for _, tt := range tests {
+		if got := ValidRegNum(tt.regNum); got != tt.valid {
+			t.Errorf("%s plate %q: valid = %v, want %v", tt.format, tt.regNum, got, tt.valid)
+		}
+	}
+}
+
+func TestValidRegNumGeneric(t *testing.T) {
+	tests := []struct {
+		regNum string
+		valid  bool
+	}{
+		{"ABC123", true},
+		{"ж 123 дб", true},
+		{"A1-2 3", true},
+		{"A1!", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidRegNum(tt.regNum); got != tt.valid {
+			t.Errorf("ValidRegNum(%q) = %v, want %v", tt.regNum, got, tt.valid)
+		}
+	}
+}
+
+func TestSetPlateCountryUnknown(t *testing.T) {
+	if err := SetPlateCountry("XX"); err == nil {
+		t.Error("SetPlateCountry(XX) succeeded, want an error")
+	}
+	if plateCountry != "" {
+		t.Errorf("plateCountry = %q after a failed call, want it unchanged", plateCountry)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Column types the stores can scan, as reported by Postgres in
+// information_schema and by SQLite as the declared type.
+var (
+	integerTypes = []string{"integer", "bigint", "smallint"}
+	textTypes    = []string{"character varying", "varchar", "text", "character"}
+	dateTypes    = []string{"date", "timestamp without time zone", "timestamp with time zone", "timestamp", "datetime"}
+)
+
+// expectedColumn is a column the stores read or write.
+type expectedColumn struct {
+	Table  string
+	Column string
+	Types  []string
+}
+
+// expectedSchema lists every column used by PostgresStore and SQLiteStore.
+var expectedSchema = []expectedColumn{
+	{"people", "id", integerTypes},
+	{"people", "name", textTypes},
+	{"people", "surname", textTypes},
+	{"people", "patronymic", textTypes},
+	{"cars", "id", integerTypes},
+	{"cars", "reg_num", textTypes},
+	{"cars", "mark", textTypes},
+	{"cars", "model", textTypes},
+	{"cars", "year", integerTypes},
+	{"cars", "owner_id", integerTypes},
+	{"ownership_history", "id", integerTypes},
+	{"ownership_history", "car_id", integerTypes},
+	{"ownership_history", "person_id", integerTypes},
+	{"ownership_history", "started_at", dateTypes},
+	{"ownership_history", "ended_at", dateTypes},
+}
+
+// SchemaDrift is a column the store uses that is missing or has an
+// incompatible type. Got is empty for a missing column.
+type SchemaDrift struct {
+	Table  string   `json:"table"`
+	Column string   `json:"column"`
+	Want   []string `json:"want"`
+	Got    string   `json:"got,omitempty"`
+}
+
+func (d SchemaDrift) String() string {
+	if d.Got == "" {
+		return fmt.Sprintf("%s.%s: missing, want %s", d.Table, d.Column, strings.Join(d.Want, " or "))
+	}
+	return fmt.Sprintf("%s.%s: type %s, want %s", d.Table, d.Column, d.Got, strings.Join(d.Want, " or "))
+}
+
+// schemaChecker is a Database whose schema can be compared with
+// expectedSchema.
+type schemaChecker interface {
+	CheckSchema(ctx context.Context) ([]SchemaDrift, error)
+}
+
+// compareSchema returns the drift of actual, which maps "table.column" to
+// the lower-cased column type, from expectedSchema.
+func compareSchema(actual map[string]string) []SchemaDrift {
+	var drift []SchemaDrift
+	for _, col := range expectedSchema {
+		got, ok := actual[col.Table+"."+col.Column]
+		if ok && slices.Contains(col.Types, got) {
+			continue
+		}
+		drift = append(drift, SchemaDrift{Table: col.Table, Column: col.Column, Want: col.Types, Got: got})
+	}
+	return drift
+}
+
+// schemaTables returns the tables of expectedSchema in order.
+func schemaTables() []string {
+	var tables []string
+	for _, col := range expectedSchema {
+		if !slices.Contains(tables, col.Table) {
+			tables = append(tables, col.Table)
+		}
+	}
+	return tables
+}
+
+func (s *PostgresStore) CheckSchema(ctx context.Context) ([]SchemaDrift, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT table_name, column_name, data_type FROM information_schema.columns
+        WHERE table_schema = current_schema()`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actual := map[string]string{}
+	for rows.Next() {
+		var table, column, dataType string
+		if err := rows.Scan(&table, &column, &dataType); err != nil {
+			return nil, err
+		}
+		actual[table+"."+column] = strings.ToLower(dataType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return compareSchema(actual), nil
+}
+
+func (s *SQLiteStore) CheckSchema(ctx context.Context) ([]SchemaDrift, error) {
+	actual := map[string]string{}
+	for _, table := range schemaTables() {
+		rows, err := s.db.QueryContext(ctx, `SELECT name, type FROM pragma_table_info(?)`, table)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var column, declared string
+			if err := rows.Scan(&column, &declared); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			// Drop the length of declarations like VARCHAR(255).
+			declared, _, _ = strings.Cut(declared, "(")
+			actual[table+"."+column] = strings.ToLower(strings.TrimSpace(declared))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return compareSchema(actual), nil
+}
+
+// CheckSchemaOnStartup compares the schema of db with the columns the store
+// uses. SCHEMA_CHECK decides what happens on drift: "strict", the default,
+// returns an error listing it, "report" only logs it, leaving it to the
+// health endpoint, and "off" skips the check.
+func CheckSchemaOnStartup(ctx context.Context, db Database, logger *slog.Logger) error {
+	mode, ok := os.LookupEnv("SCHEMA_CHECK")
+	if !ok || mode == "" {
+		mode = "strict"
+	}
+	switch mode {
+	case "strict", "report":
+	case "off":
+		return nil
+	default:
+		return fmt.Errorf("unknown SCHEMA_CHECK %q", mode)
+	}
+	checker, ok := db.(schemaChecker)
+	if !ok {
+		return nil
+	}
+
+	drift, err := checker.CheckSchema(ctx)
+	if err != nil {
+		return fmt.Errorf("checking database schema: %w", err)
+	}
+	if len(drift) == 0 {
+		return nil
+	}
+	lines := make([]string, len(drift))
+	for i, d := range drift {
+		lines[i] = "  " + d.String()
+		logger.Warn("schema drift", "table", d.Table, "column", d.Column, "want", d.Want, "got", d.Got)
+	}
+	if mode == "report" {
+		return nil
+	}
+	return fmt.Errorf("database schema does not match the store:\n%s", strings.Join(lines, "\n"))
+}
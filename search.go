@@ -0,0 +1,99 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// fullTextRank is the score a full-text match adds, standing in for the
+// ts_rank of PostgresStore.
+const fullTextRank = 0.06
+
+// rankCars searches cars in memory for stores without the Postgres search
+// functions. It approximates SearchCars of PostgresStore: every word of
+// text must occur in the car or owner words for a full-text match, and
+// fuzzy matches compare words by pg_trgm trigram similarity. Cars must be
+// ordered by ID.
+func rankCars(cars []*Car, text string, limit int) []*SearchResult {
+	words := searchWords(text)
+	regNum := NormalizeRegNum(text)
+	results := []*SearchResult{}
+	for _, car := range cars {
+		carWords := searchWords(car.Mark + " " + car.Model + " " + car.RegNum)
+		var ownerWords []string
+		if car.Owner.ID != 0 {
+			ownerWords = searchWords(car.Owner.Name + " " + car.Owner.Surname + " " + car.Owner.Patronymic)
+		}
+		fullText := containsWords(carWords, words) || (ownerWords != nil && containsWords(ownerWords, words))
+		similarity := max(wordSimilarity(words, carWords), wordSimilarity(words, ownerWords))
+		if !fullText && similarity < searchSimilarityThreshold && (regNum == "" || !strings.Contains(car.RegNum, regNum)) {
+			continue
+		}
+		score := similarity
+		if fullText {
+			score += fullTextRank
+		}
+		results = append(results, &SearchResult{Car: car, Score: score})
+	}
+	slices.SortStableFunc(results, func(a, b *SearchResult) int { return cmp.Compare(b.Score, a.Score) })
+	return results[:min(limit, len(results))]
+}
+
+// searchWords splits s into lower-cased words of letters and digits.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func containsWords(words, wanted []string) bool {
+	if len(wanted) == 0 {
+		return false
+	}
+	for _, w := range wanted {
+		if !slices.Contains(words, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// wordSimilarity averages, over the query words, the trigram similarity of
+// the most similar document word.
+func wordSimilarity(query, doc []string) float64 {
+	if len(query) == 0 || len(doc) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, q := range query {
+		best := 0.0
+		for _, d := range doc {
+			best = max(best, trigramSimilarity(trigrams(q), trigrams(d)))
+		}
+		sum += best
+	}
+	return sum / float64(len(query))
+}
+
+// trigrams returns the trigrams of a word padded like pg_trgm does, with
+// two spaces in front and one behind.
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+func trigramSimilarity(a, b map[string]bool) float64 {
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -12,20 +12,24 @@ import (
 )
 
 type Server struct {
-	addr   string
-	db     Database
-	logger *slog.Logger
+	addr          string
+	db            Database
+	provider      CarInfoProvider
+	enrichWorkers int
+	logger        *slog.Logger
 }
 
-func NewServer(addr string, db Database, logger *slog.Logger) *Server {
+func NewServer(addr string, db Database, provider CarInfoProvider, logger *slog.Logger) *Server {
 	return &Server{
-		addr:   addr,
-		db:     db,
-		logger: logger,
+		addr:          addr,
+		db:            db,
+		provider:      provider,
+		enrichWorkers: defaultEnrichWorkers,
+		logger:        logger,
 	}
 }
 
-func WriteJSON(w http.ResponseWriter, status int, v ...any) error {
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(status)
 	return json.NewEncoder(w).Encode(v)
@@ -34,7 +38,10 @@ func WriteJSON(w http.ResponseWriter, status int, v ...any) error {
 func HTTPHandleFunc(f APIFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := f(w, r); err != nil {
-			WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+			if withholdsDetail(err) {
+				slog.Error("request failed", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+			}
+			WriteProblem(w, r, err)
 		}
 	}
 }
@@ -49,10 +56,29 @@ func (s *Server) Start() {
 		httpSwagger.DomID("swagger-ui"),
 	)).Methods(http.MethodGet)
 	// init routes
+	router.HandleFunc("/health", HTTPHandleFunc(s.HealthHandler)).Methods("GET")
 	router.HandleFunc("/cars/get", HTTPHandleFunc(s.GetCarsHandler)).Methods("GET")
 	router.HandleFunc("/cars/delete/{id}", HTTPHandleFunc(s.DeleteCarHandler)).Methods("DELETE")
 	router.HandleFunc("/cars/update/{id}", HTTPHandleFunc(s.UpdateCarHandler)).Methods("PATCH")
+	router.HandleFunc("/cars/update/{id}", HTTPHandleFunc(s.ReplaceCarHandler)).Methods("PUT")
 	router.HandleFunc("/cars/add", HTTPHandleFunc(s.AddCarHandler)).Methods("POST")
+	router.HandleFunc("/cars/{id:[0-9]+}", HTTPHandleFunc(s.GetCarHandler)).Methods("GET")
+	router.HandleFunc("/cars/{id:[0-9]+}", HTTPHandleFunc(s.UpdateCarHandler)).Methods("PATCH")
+	router.HandleFunc("/cars/{id:[0-9]+}", HTTPHandleFunc(s.ReplaceCarHandler)).Methods("PUT")
+	router.HandleFunc("/cars/stats", HTTPHandleFunc(s.GetCarStatsHandler)).Methods("GET")
+	router.HandleFunc("/cars/search", HTTPHandleFunc(s.SearchCarsHandler)).Methods("GET")
+	router.HandleFunc("/cars/by-reg/{regNum}", HTTPHandleFunc(s.GetCarByRegNumHandler)).Methods("GET")
+	router.HandleFunc("/cars/{id:[0-9]+}/transfer", HTTPHandleFunc(s.TransferCarHandler)).Methods("POST")
+	router.HandleFunc("/cars/{id:[0-9]+}/owners", HTTPHandleFunc(s.GetCarOwnersHandler)).Methods("GET")
+	router.HandleFunc("/people/get", HTTPHandleFunc(s.GetPeopleHandler)).Methods("GET")
+	router.HandleFunc("/people/add", HTTPHandleFunc(s.AddPersonHandler)).Methods("POST")
+	router.HandleFunc("/people/update/{id}", HTTPHandleFunc(s.PatchPersonHandler)).Methods("PATCH")
+	router.HandleFunc("/people/update/{id}", HTTPHandleFunc(s.ReplacePersonHandler)).Methods("PUT")
+	router.HandleFunc("/people/delete/{id}", HTTPHandleFunc(s.DeletePersonHandler)).Methods("DELETE")
+	router.HandleFunc("/people/{id:[0-9]+}", HTTPHandleFunc(s.GetPersonHandler)).Methods("GET")
+	router.HandleFunc("/people/{id:[0-9]+}", HTTPHandleFunc(s.PatchPersonHandler)).Methods("PATCH")
+	router.HandleFunc("/people/{id:[0-9]+}", HTTPHandleFunc(s.ReplacePersonHandler)).Methods("PUT")
+	router.HandleFunc("/people/{id:[0-9]+}/cars", HTTPHandleFunc(s.GetPersonCarsHandler)).Methods("GET")
 	s.logger.Info("Starting server...", "port", s.addr)
 	err := http.ListenAndServe(s.addr, handlers.CORS()(router))
 	if err != nil {
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"strings"
+)
+
+// SortKey is one component of a sort order, e.g. "-year".
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+func (k SortKey) String() string {
+	if k.Desc {
+		return "-" + k.Field
+	}
+	return k.Field
+}
+
+// carSortColumns is the allowlist of sortable car fields and the SQL they
+// sort by. NULLs are folded so that ordering is total.
+var carSortColumns = map[string]string{
+	"id":            "c.id",
+	"year":          "COALESCE(c.year, 0)",
+	"mark":          "c.mark",
+	"regNum":        "c.reg_num",
+	"owner.surname": "COALESCE(p.surname, '')",
+}
+
+// ParseCarSort reads the comma-separated sort parameter, recording
+// violations in v. A leading "-" sorts descending. The result always ends
+// with id as a tiebreaker so that pages are stable.
+func ParseCarSort(r *http.Request, v *Validator) []SortKey {
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, field := range strings.Split(r.URL.Query().Get("sort"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if _, ok := carSortColumns[key.Field]; !ok {
+			v.Add("sort", "unsupported", "cannot sort by %q", key.Field)
+			continue
+		}
+		if seen[key.Field] {
+			v.Add("sort", "duplicate", "%q is listed more than once", key.Field)
+			continue
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	if !seen["id"] {
+		keys = append(keys, SortKey{Field: "id"})
+	}
+	return keys
+}
+
+// carOrderBy turns validated sort keys into ORDER BY expressions.
+func carOrderBy(keys []SortKey) []string {
+	exprs := make([]string, len(keys))
+	for i, key := range keys {
+		exprs[i] = carSortColumns[key.Field]
+		if key.Desc {
+			exprs[i] += " DESC"
+		}
+	}
+	return exprs
+}
+
+// compareCars orders a and b by keys like carOrderBy does, for stores that
+// sort in memory. Strings compare bytewise.
+func compareCars(a, b *Car, keys []SortKey) int {
+	return compareSortValues(a, keys, func(i int) any { return carSortValue(b, keys[i].Field) })
+}
+
+// compareSortValues compares car with the sort key values given by value,
+// honoring the direction of each key. It matches the order keysetCondition
+// continues in.
+func compareSortValues(car *Car, keys []SortKey, value func(i int) any) int {
+	for i, key := range keys {
+		var c int
+		switch v := carSortValue(car, key.Field).(type) {
+		case int:
+			c = cmp.Compare(v, value(i).(int))
+		case string:
+			c = cmp.Compare(v, value(i).(string))
+		}
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Database {
+		return newTestSQLiteStore(t)
+	})
+}
+
+func TestSQLiteStoreConstraintErrors(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	cars := []*Car{{RegNum: "A111AA77", Mark: "Lada"}, {RegNum: "B222BB77", Mark: "Lada"}}
+	if _, err := store.AddCars(cars, ConflictReject); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.UpdateCarByID(cars[1].ID, &Car{RegNum: "A111AA77", Mark: "Lada"})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("duplicate reg num: error = %v, want a conflict", err)
+	}
+	err = store.UpdateCarByID(cars[1].ID, &Car{RegNum: "B222BB77", Mark: "Lada", Owner: People{ID: 999}})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("unknown owner: error = %v, want a validation error", err)
+	}
+}
+
+// TestSQLiteMigrateLegacySchema migrates a schema created by initTables
+// before migrations were tracked, which already has the ownership history
+// and the unique plates.
+func TestSQLiteMigrateLegacySchema(t *testing.T) {
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "cartest.db"))
+	store, err := NewSQLiteStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.db.Close() })
+	_, err = store.db.Exec(`
+        CREATE TABLE people (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(255) NOT NULL,
+            surname VARCHAR(255) NOT NULL, patronymic VARCHAR(255));
+        CREATE TABLE cars (id INTEGER PRIMARY KEY AUTOINCREMENT, reg_num VARCHAR(20) NOT NULL UNIQUE,
+            mark VARCHAR(255) NOT NULL, model VARCHAR(255) NOT NULL, year INTEGER,
+            owner_id INTEGER REFERENCES people(id));
+        CREATE TABLE ownership_history (id INTEGER PRIMARY KEY AUTOINCREMENT,
+            car_id INTEGER NOT NULL REFERENCES cars(id) ON DELETE CASCADE,
+            person_id INTEGER NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+            started_at DATE NOT NULL, ended_at DATE);
+        INSERT INTO people (name, surname) VALUES ('Ivan', 'Ivanov');
+        INSERT INTO cars (reg_num, mark, model, owner_id) VALUES ('а123вс 77', 'Lada', 'Vesta', 1);
+        INSERT INTO ownership_history (car_id, person_id, started_at) VALUES (1, 1, '2020-01-01');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := store.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 4 {
+		t.Errorf("applied %d migrations, want 4 after the renamed column", applied)
+	}
+	if _, err := store.GetCarByRegNum("A123BC77"); err != nil {
+		t.Errorf("plate was not normalized: %v", err)
+	}
+	history, err := store.GetCarOwnershipHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Since.Format(time.DateOnly) != "2020-01-01" {
+		t.Errorf("history = %+v, want the existing period only", history)
+	}
+}
+
+// newTestSQLiteStore returns a migrated SQLiteStore on a temporary file.
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "cartest.db"))
+	store, err := NewSQLiteStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.db.Close() })
+	m, err := store.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+)
+
+// StatBucket is the number of cars sharing a key, such as a mark, a year
+// or an age range.
+type StatBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// ModelStat is the number of cars of one model. Models are grouped per mark
+// since different marks may use the same model name.
+type ModelStat struct {
+	Mark  string `json:"mark"`
+	Model string `json:"model"`
+	Count int    `json:"count"`
+}
+
+// CarStats summarizes the cars matching a filter. Cars without a year are
+// counted under the "unknown" key of the year, decade and age groups.
+type CarStats struct {
+	Total           int          `json:"total"`
+	ByMark          []StatBucket `json:"byMark"`
+	ByModel         []ModelStat  `json:"byModel"`
+	ByYear          []StatBucket `json:"byYear"`
+	ByDecade        []StatBucket `json:"byDecade"`
+	AgeDistribution []StatBucket `json:"ageDistribution"`
+	MultiCarOwners  int          `json:"multiCarOwners"`
+	TopMarks        []StatBucket `json:"topMarks"`
+}
+
+const unknownStatKey = "unknown"
+
+// topStatBuckets returns the first n buckets, which must be ordered by
+// count.
+func topStatBuckets(buckets []StatBucket, n int) []StatBucket {
+	return buckets[:min(n, len(buckets))]
+}
+
+// carStats aggregates cars in memory the way GetCarStats of PostgresStore
+// does in SQL. TopMarks is left to the caller.
+func carStats(cars []*Car, currentYear int) *CarStats {
+	stats := &CarStats{Total: len(cars)}
+	marks := map[string]int{}
+	models := map[[2]string]int{}
+	years := map[int]int{}
+	decades := map[int]int{}
+	ages := map[string]int{}
+	owned := map[int]int{}
+	for _, car := range cars {
+		marks[car.Mark]++
+		models[[2]string{car.Mark, car.Model}]++
+		years[car.Year]++
+		if car.Year != 0 {
+			decades[car.Year/10*10]++
+		}
+		ages[carAgeKey(car.Year, currentYear)]++
+		if car.Owner.ID != 0 {
+			owned[car.Owner.ID]++
+		}
+	}
+
+	stats.ByMark = []StatBucket{}
+	for mark, n := range marks {
+		stats.ByMark = append(stats.ByMark, StatBucket{Key: mark, Count: n})
+	}
+	slices.SortFunc(stats.ByMark, func(a, b StatBucket) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Key, b.Key))
+	})
+	stats.ByModel = []ModelStat{}
+	for model, n := range models {
+		stats.ByModel = append(stats.ByModel, ModelStat{Mark: model[0], Model: model[1], Count: n})
+	}
+	slices.SortFunc(stats.ByModel, func(a, b ModelStat) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Mark, b.Mark), cmp.Compare(a.Model, b.Model))
+	})
+	stats.ByYear = yearBuckets(years, func(year int) string { return strconv.Itoa(year) })
+	stats.ByDecade = yearBuckets(decades, func(decade int) string { return strconv.Itoa(decade) + "s" })
+	if n := years[0]; n > 0 {
+		stats.ByDecade = append(stats.ByDecade, StatBucket{Key: unknownStatKey, Count: n})
+	}
+	stats.AgeDistribution = []StatBucket{}
+	for _, key := range []string{"0-2", "3-5", "6-10", "11-20", "21+", unknownStatKey} {
+		if n := ages[key]; n > 0 {
+			stats.AgeDistribution = append(stats.AgeDistribution, StatBucket{Key: key, Count: n})
+		}
+	}
+	for _, n := range owned {
+		if n > 1 {
+			stats.MultiCarOwners++
+		}
+	}
+	return stats
+}
+
+// yearBuckets orders counts by year with the unknown year 0 last.
+func yearBuckets(counts map[int]int, key func(year int) string) []StatBucket {
+	buckets := []StatBucket{}
+	for _, year := range sortedKeys(counts) {
+		if year != 0 {
+			buckets = append(buckets, StatBucket{Key: key(year), Count: counts[year]})
+		}
+	}
+	if n := counts[0]; n > 0 {
+		buckets = append(buckets, StatBucket{Key: unknownStatKey, Count: n})
+	}
+	return buckets
+}
+
+// carAgeKey returns the age range of carAgeBucket for a car year.
+func carAgeKey(year, currentYear int) string {
+	age := currentYear - year
+	switch {
+	case year == 0:
+		return unknownStatKey
+	case age <= 2:
+		return "0-2"
+	case age <= 5:
+		return "3-5"
+	case age <= 10:
+		return "6-10"
+	case age <= 20:
+		return "11-20"
+	}
+	return "21+"
+}
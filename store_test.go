@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testStore runs the behaviour every Database must share. newDB returns an
+// empty store for each subtest.
+func testStore(t *testing.T, newDB func(t *testing.T) Database) {
+	t.Run("Filter", func(t *testing.T) { testStoreFilter(t, newDB(t)) })
+	t.Run("Pagination", func(t *testing.T) { testStorePagination(t, newDB(t)) })
+	t.Run("SortAndKeyset", func(t *testing.T) { testStoreSortAndKeyset(t, newDB(t)) })
+	t.Run("ConflictModes", func(t *testing.T) { testStoreConflictModes(t, newDB(t)) })
+	t.Run("NotFound", func(t *testing.T) { testStoreNotFound(t, newDB(t)) })
+	t.Run("OwnershipHistory", func(t *testing.T) { testStoreOwnershipHistory(t, newDB(t)) })
+	t.Run("ModifyCarOwner", func(t *testing.T) { testStoreModifyCarOwner(t, newDB(t)) })
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Database { return NewMemoryStore() })
+}
+
+// TestPostgresStore runs against the database named by POSTGRES_TEST_DSN,
+// which it migrates and empties.
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := newPostgresStore(db)
+	m, err := store.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, func(t *testing.T) Database {
+		if _, err := db.Exec(`TRUNCATE ownership_history, cars, people RESTART IDENTITY`); err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
+
+// seedCars adds a fixed fleet and returns it with the IDs set.
+func seedCars(t *testing.T, db Database) []*Car {
+	t.Helper()
+	ivanov := People{Name: "Ivan", Surname: "Ivanov"}
+	cars := []*Car{
+		{RegNum: "A001AA77", Mark: "Lada", Model: "Vesta", Year: 2019, Owner: ivanov},
+		{RegNum: "B002BB77", Mark: "Lada", Model: "Granta", Year: 2015, Owner: People{Name: "Petr", Surname: "Petrov"}},
+		{RegNum: "C003CC77", Mark: "Kia", Model: "Rio", Year: 2019, Owner: ivanov},
+		{RegNum: "E004EE77", Mark: "Volvo", Model: "XC90", Year: 2021},
+		{RegNum: "K005KK77", Mark: "Audi", Model: "A4 100%", Owner: People{Name: "Анна", Surname: "Сидорова"}},
+	}
+	if _, err := db.AddCars(cars, ConflictReject); err != nil {
+		t.Fatal(err)
+	}
+	return cars
+}
+
+func regNums(cars []*Car) []string {
+	nums := make([]string, len(cars))
+	for i, car := range cars {
+		nums[i] = car.RegNum
+	}
+	return nums
+}
+
+var byID = []SortKey{{Field: "id"}}
+
+func testStoreFilter(t *testing.T, db Database) {
+	seedCars(t, db)
+	tests := []struct {
+		name   string
+		filter CarFilter
+		want   []string
+	}{
+		{"none", CarFilter{}, []string{"A001AA77", "B002BB77", "C003CC77", "E004EE77", "K005KK77"}},
+		{"mark", CarFilter{Marks: []string{"lada"}}, []string{"A001AA77", "B002BB77"}},
+		{"marks", CarFilter{Marks: []string{"LADA", "kia"}}, []string{"A001AA77", "B002BB77", "C003CC77"}},
+		{"model", CarFilter{Model: "vesta"}, []string{"A001AA77"}},
+		{"year", CarFilter{Year: 2019}, []string{"A001AA77", "C003CC77"}},
+		{"year range", CarFilter{YearFrom: 2016, YearTo: 2020}, []string{"A001AA77", "C003CC77"}},
+		{"mark prefix", CarFilter{MarkPrefix: "la"}, []string{"A001AA77", "B002BB77"}},
+		{"mark contains", CarFilter{MarkContains: "OLV"}, []string{"E004EE77"}},
+		{"literal percent", CarFilter{ModelContains: "0%"}, []string{"K005KK77"}},
+		{"literal underscore", CarFilter{ModelPrefix: "_"}, nil},
+		{"reg num prefix", CarFilter{RegNumPrefix: "B0"}, []string{"B002BB77"}},
+		{"reg num contains", CarFilter{RegNumContains: "05K"}, []string{"K005KK77"}},
+		{"owner surname", CarFilter{OwnerSurname: "iva"}, []string{"A001AA77", "C003CC77"}},
+		{"cyrillic owner surname", CarFilter{OwnerSurname: "СИД"}, []string{"K005KK77"}},
+		{"combined", CarFilter{OwnerName: "ivan", Marks: []string{"kia"}}, []string{"C003CC77"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cars, total, err := db.GetCars(CarQuery{Page: 1, PageSize: 100, Filter: tt.filter, Sort: byID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := regNums(cars); len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cars = %v, want %v", got, tt.want)
+			}
+			if total != len(tt.want) {
+				t.Errorf("total = %d, want %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func testStorePagination(t *testing.T, db Database) {
+	seedCars(t, db)
+	filter := CarFilter{Marks: []string{"lada", "kia"}}
+	tests := []struct {
+		page, pageSize int
+		want           []string
+	}{
+		{1, 2, []string{"A001AA77", "B002BB77"}},
+		{2, 2, []string{"C003CC77"}},
+		{3, 2, nil},
+		{1000000, 100, nil},
+	}
+	for _, tt := range tests {
+		cars, total, err := db.GetCars(CarQuery{Page: tt.page, PageSize: tt.pageSize, Filter: filter, Sort: byID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := regNums(cars); len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("page %d of %d: cars = %v, want %v", tt.page, tt.pageSize, got, tt.want)
+		}
+		if total != 3 {
+			t.Errorf("page %d of %d: total = %d, want 3", tt.page, tt.pageSize, total)
+		}
+	}
+}
+
+func testStoreSortAndKeyset(t *testing.T, db Database) {
+	seedCars(t, db)
+	keys := []SortKey{{Field: "year", Desc: true}, {Field: "mark"}, {Field: "id"}}
+	want := []string{"E004EE77", "C003CC77", "A001AA77", "B002BB77", "K005KK77"}
+
+	cars, _, err := db.GetCars(CarQuery{Page: 1, PageSize: 100, Sort: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := regNums(cars); !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted cars = %v, want %v", got, want)
+	}
+
+	var got []string
+	q := CarQuery{Page: 1, PageSize: 2, Sort: keys, Keyset: true}
+	for range want {
+		cars, _, err := db.GetCars(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cars) <= q.PageSize {
+			got = append(got, regNums(cars)...)
+			break
+		}
+		page := cars[:q.PageSize]
+		got = append(got, regNums(page)...)
+		last := page[len(page)-1]
+		q.After = nil
+		for _, key := range keys {
+			q.After = append(q.After, carSortValue(last, key.Field))
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("keyset pages = %v, want %v", got, want)
+	}
+}
+
+func testStoreConflictModes(t *testing.T, db Database) {
+	seeded := seedCars(t, db)
+
+	duplicate := func() *Car {
+		return &Car{RegNum: "a 001 aa 77", Mark: "Lada", Model: "Niva", Year: 2020}
+	}
+	fresh := func(regNum string) *Car {
+		return &Car{RegNum: regNum, Mark: "Kia", Model: "Ceed", Year: 2022}
+	}
+
+	_, err := db.AddCars([]*Car{fresh("M006MM77"), duplicate()}, ConflictReject)
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("reject: err = %v, want a conflict", err)
+	}
+	if _, err := db.GetCarByRegNum("M006MM77"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reject: batch was partially stored, err = %v", err)
+	}
+
+	statuses, err := db.AddCars([]*Car{fresh("M007MM77"), duplicate()}, ConflictSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []AddCarStatus{AddCarStatusCreated, AddCarStatusSkipped}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("skip: statuses = %v, want %v", statuses, want)
+	}
+	if car, err := db.GetCarByRegNum("A001AA77"); err != nil || car.Model != "Vesta" {
+		t.Errorf("skip: car = %+v, %v, want it unchanged", car, err)
+	}
+
+	upsert := duplicate()
+	statuses, err = db.AddCars([]*Car{upsert}, ConflictUpsert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []AddCarStatus{AddCarStatusUpdated}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("upsert: statuses = %v, want %v", statuses, want)
+	}
+	if upsert.ID != seeded[0].ID {
+		t.Errorf("upsert: ID = %d, want %d", upsert.ID, seeded[0].ID)
+	}
+	car, err := db.GetCarByRegNum("A001AA77")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if car.ID != seeded[0].ID || car.Model != "Niva" || car.Year != 2020 {
+		t.Errorf("upsert: car = %+v", car)
+	}
+}
+
+func testStoreNotFound(t *testing.T, db Database) {
+	seedCars(t, db)
+	const missing = 999
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetCarByID", func() error { _, err := db.GetCarByID(missing); return err }},
+		{"GetCarByRegNum", func() error { _, err := db.GetCarByRegNum("X999XX99"); return err }},
+		{"DeleteCarByID", func() error { return db.DeleteCarByID(missing) }},
+		{"UpdateCarByID", func() error {
+			return db.UpdateCarByID(missing, &Car{RegNum: "X999XX99", Mark: "Kia", Model: "Rio"})
+		}},
+		{"ModifyCarByID", func() error { return db.ModifyCarByID(missing, func(*Car) error { return nil }) }},
+		{"GetPersonByID", func() error { _, err := db.GetPersonByID(missing); return err }},
+		{"UpdatePersonByID", func() error {
+			return db.UpdatePersonByID(missing, &People{Name: "Ivan", Surname: "Ivanov"})
+		}},
+		{"ModifyPersonByID", func() error { return db.ModifyPersonByID(missing, func(*People) error { return nil }) }},
+		{"DeletePersonByID", func() error { return db.DeletePersonByID(missing) }},
+		{"TransferCar", func() error {
+			_, err := db.TransferCar(missing, &Transfer{ToOwnerID: 1, EffectiveDate: time.Now()})
+			return err
+		}},
+		{"GetCarOwnershipHistory", func() error { _, err := db.GetCarOwnershipHistory(missing); return err }},
+		{"GetPersonOwnershipHistory", func() error { _, err := db.GetPersonOwnershipHistory(missing); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrNotFound) {
+				t.Errorf("err = %v, want not found", err)
+			}
+		})
+	}
+}
+
+func testStoreOwnershipHistory(t *testing.T, db Database) {
+	car := seedCars(t, db)[0]
+	previous := car.Owner.ID
+	next := &People{Name: "Sergey", Surname: "Smirnov"}
+	if err := db.AddPerson(next); err != nil {
+		t.Fatal(err)
+	}
+	effective := time.Now()
+
+	tomorrow := effective.AddDate(0, 0, 1)
+	if _, err := db.TransferCar(car.ID, &Transfer{ToOwnerID: next.ID, EffectiveDate: tomorrow}); !errors.Is(err, ErrValidation) {
+		t.Errorf("transfer dated tomorrow: err = %v, want a validation error", err)
+	}
+	if current, err := db.GetCarByID(car.ID); err != nil || current.Owner.ID != previous {
+		t.Errorf("after a rejected transfer: car = %+v, %v, want owner %d", current, err, previous)
+	}
+	if _, err := db.TransferCar(car.ID, &Transfer{FromOwnerID: next.ID, ToOwnerID: previous, EffectiveDate: effective}); !errors.Is(err, ErrConflict) {
+		t.Errorf("transfer from a non-owner: err = %v, want a conflict", err)
+	}
+	if _, err := db.TransferCar(car.ID, &Transfer{FromOwnerID: previous, ToOwnerID: next.ID, EffectiveDate: effective}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkCarHistory := func() {
+		t.Helper()
+		history, err := db.GetCarOwnershipHistory(car.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 {
+			t.Fatalf("car history has %d entries, want 2", len(history))
+		}
+		first, second := history[0], history[1]
+		if first.Owner.ID != previous || first.Until == nil || first.Until.Format(time.DateOnly) != effective.Format(time.DateOnly) {
+			t.Errorf("first ownership = %+v, want owner %d until %s", first, previous, effective.Format(time.DateOnly))
+		}
+		if second.Owner.ID != next.ID || second.Until != nil || second.Since.Format(time.DateOnly) != effective.Format(time.DateOnly) {
+			t.Errorf("second ownership = %+v, want owner %d open since %s", second, next.ID, effective.Format(time.DateOnly))
+		}
+	}
+	checkCarHistory()
+
+	history, err := db.GetPersonOwnershipHistory(next.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].CarID != car.ID || history[0].Car.ID != car.ID || history[0].Car.RegNum != car.RegNum {
+		t.Errorf("person history = %+v, want car %d", history, car.ID)
+	}
+
+	if err := db.DeletePersonByID(previous); !errors.Is(err, ErrConflict) {
+		t.Errorf("deleting a former owner: err = %v, want a conflict", err)
+	}
+	checkCarHistory()
+
+	unrelated := &People{Name: "Olga", Surname: "Orlova"}
+	if err := db.AddPerson(unrelated); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeletePersonByID(unrelated.ID); err != nil {
+		t.Errorf("deleting a person without cars: %v", err)
+	}
+}
+
+func testStoreModifyCarOwner(t *testing.T, db Database) {
+	cars := seedCars(t, db)
+	first, second := cars[0], cars[2]
+	err := db.ModifyCarByID(first.ID, func(car *Car) error {
+		car.Owner.Patronymic = "Ivanovich"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := People{ID: first.Owner.ID, Name: "Ivan", Surname: "Ivanov", Patronymic: "Ivanovich"}
+	for _, id := range []int{first.ID, second.ID} {
+		car, err := db.GetCarByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if car.Owner != want {
+			t.Errorf("car %d: owner = %+v, want %+v", id, car.Owner, want)
+		}
+	}
+	history, err := db.GetCarOwnershipHistory(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Errorf("car history has %d entries, want 1", len(history))
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const minCarYear = 1886
+
+// ListParams are the pagination query parameters shared by list endpoints.
+// The page bound keeps the row offset far from integer overflow.
+type ListParams struct {
+	Page     int `json:"page" validate:"min=1,max=1000000"`
+	PageSize int `json:"page_size" validate:"min=1,max=100"`
+}
+
+// Validator collects field violations so they can be reported together.
+//
+// Struct validation is driven by `validate` tags with comma-separated
+// rules: required, omitempty (skip the field when it has its zero value),
+// ref (skip a nested struct whose ID is set, as it references an existing
+// record), min=N and max=N (string length in characters, slice length or
+// integer bounds) and the named checks registered in fieldChecks. Nested
+// structs are validated recursively, with JSON names joined by dots as
+// field paths.
+type Validator struct {
+	fields []FieldError
+}
+
+// fieldCheck validates a single value and returns a code and message on
+// failure.
+type fieldCheck func(v reflect.Value) (code, message string, ok bool)
+
+var fieldChecks = map[string]fieldCheck{
+	"regnum": func(v reflect.Value) (string, string, bool) {
+		if ValidRegNum(v.String()) {
+			return "", "", true
+		}
+		if plateCountry != "" {
+			return "invalid_format", "is not a valid " + plateCountry + " registration number", false
+		}
+		return "invalid_format", "must contain only letters, digits, spaces and hyphens", false
+	},
+	"year": func(v reflect.Value) (string, string, bool) {
+		year, maxYear := int(v.Int()), time.Now().Year()+1
+		if year == 0 || (year >= minCarYear && year <= maxYear) {
+			return "", "", true
+		}
+		return "out_of_range", fmt.Sprintf("must be between %d and %d", minCarYear, maxYear), false
+	},
+}
+
+func (v *Validator) Add(field, code, format string, args ...any) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// failed reports whether a violation was already recorded for field.
+func (v *Validator) failed(field string) bool {
+	for _, f := range v.fields {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns a validation error listing every collected violation, or nil.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	messages := make([]string, len(v.fields))
+	for i, f := range v.fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return &Error{
+		Kind:    KindValidation,
+		Message: "validation failed: " + strings.Join(messages, "; "),
+		Fields:  v.fields,
+	}
+}
+
+// Struct validates the tagged fields of s, which must be a struct or a
+// pointer to one. prefix is prepended to the reported field paths.
+func (v *Validator) Struct(prefix string, s any) {
+	rv := reflect.Indirect(reflect.ValueOf(s))
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		name := jsonName(sf)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fv := rv.Field(i)
+		rules := strings.Split(sf.Tag.Get("validate"), ",")
+		if hasRule(rules, "omitempty") && fv.IsZero() {
+			continue
+		}
+		if hasRule(rules, "ref") && !fv.FieldByName("ID").IsZero() {
+			continue
+		}
+		v.field(name, fv, rules)
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			v.Struct(name, fv.Interface())
+		}
+	}
+}
+
+func (v *Validator) field(name string, fv reflect.Value, rules []string) {
+	if v.failed(name) {
+		return
+	}
+	for _, rule := range rules {
+		key, arg, _ := strings.Cut(rule, "=")
+		switch key {
+		case "", "omitempty", "ref":
+		case "required":
+			if fv.IsZero() || (fv.Kind() == reflect.String && strings.TrimSpace(fv.String()) == "") {
+				v.Add(name, "required", "is required")
+				return
+			}
+		case "min", "max":
+			limit, _ := strconv.Atoi(arg)
+			var n int
+			switch fv.Kind() {
+			case reflect.String:
+				n = utf8.RuneCountInString(fv.String())
+			case reflect.Slice:
+				n = fv.Len()
+			default:
+				n = int(fv.Int())
+			}
+			if key == "min" && n < limit {
+				v.Add(name, "too_small", "must be at least %d", limit)
+			}
+			if key == "max" && n > limit {
+				v.Add(name, "too_large", "must be at most %d", limit)
+			}
+		default:
+			check, ok := fieldChecks[key]
+			if !ok {
+				panic("validate: unknown rule " + key)
+			}
+			if code, message, ok := check(fv); !ok {
+				v.Add(name, code, "%s", message)
+			}
+		}
+	}
+}
+
+// Check validates a single value against comma-separated rules.
+func (v *Validator) Check(field string, value any, rules string) {
+	v.field(field, reflect.ValueOf(value), strings.Split(rules, ","))
+}
+
+// QueryInt parses the named query parameter, recording a violation if it
+// is missing while required or not an integer. def is returned for a
+// missing optional parameter.
+func (v *Validator) QueryInt(r *http.Request, name string, required bool, def int) int {
+	str := r.URL.Query().Get(name)
+	if str == "" {
+		if required {
+			v.Add(name, "required", "query parameter %s is required", name)
+		}
+		return def
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		v.Add(name, "invalid_integer", "query parameter %s must be an integer", name)
+		return def
+	}
+	return n
+}
+
+// ListParams reads and validates the pagination query parameters.
+func (v *Validator) ListParams(r *http.Request) ListParams {
+	params := ListParams{
+		Page:     v.QueryInt(r, "page", true, 0),
+		PageSize: v.QueryInt(r, "page_size", true, 0),
+	}
+	v.Struct("", params)
+	return params
+}
+
+// Validate checks s against its `validate` tags.
+func Validate(s any) error {
+	var v Validator
+	v.Struct("", s)
+	return v.Err()
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+func hasRule(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}